@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Subcommands understood after a @claude mention
+const (
+	actionRun      = "run"
+	actionRetry    = "retry"
+	actionCancel   = "cancel"
+	actionStatus   = "status"
+	actionContinue = "continue"
+)
+
+// defaultMaxTurns is used when a request does not pass --max-turns
+const defaultMaxTurns = 10
+
+// maxAllowedTurns caps --max-turns so a single comment cannot run unbounded
+const maxAllowedTurns = 100
+
+// knownFlags are the flags parseCommand reads; other "--" words stay in the task
+var knownFlags = map[string]bool{"max-turns": true, "ref": true, "repo": true}
+
+// The README documents "@claude-code", so both spellings are accepted
+var (
+	firstMentionRegex = regexp.MustCompile(`(?i)(?:^|[^a-zA-Z0-9.])(@claude(?:-code)?)\b`)
+	mentionRegex      = regexp.MustCompile(`(?i)@claude(?:-code)?\s*`)
+	repoFlagRegex     = regexp.MustCompile(`^[a-zA-Z0-9._-]+/[a-zA-Z0-9._-]+$`)
+)
+
+// commandUsage is posted when a command cannot be parsed
+const commandUsage = "**使い方:**\n" +
+	"- `@claude <task>` / `@claude run <task>`: タスクを実行\n" +
+	"- `@claude retry`: このIssueの直前のタスクを再実行\n" +
+	"- `@claude cancel`: 実行中のタスクをキャンセル\n" +
+	"- `@claude status`: 実行状況を表示\n" +
+	"- `@claude continue <follow-up>`: 直前のタスクの続きを依頼\n" +
+	"- `claude-cancel` ラベル: 実行中・待機中のタスクをキャンセル\n\n" +
+	"**オプション:** `--max-turns <n>` (1-100), `--repo <owner/name>`, `--ref <branch|tag|sha>`"
+
+// Command is a parsed @claude request
+type Command struct {
+	Action     string
+	Task       string
+	MaxTurns   int    // 0 when --max-turns was not given
+	Repository string // target repository from --repo
+	Ref        string // branch, tag or commit from --ref
+}
+
+// parseCommand parses the text of an issue body or comment containing a
+// @claude mention. A subcommand must directly follow the first mention;
+// otherwise the whole text is treated as a task to run. The known flags are
+// read from the line of the mention.
+func parseCommand(text string) (*Command, error) {
+	loc := firstMentionRegex.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return nil, fmt.Errorf("no @claude mention found")
+	}
+	before, after := text[:loc[2]], text[loc[3]:]
+
+	firstLine, rest := after, ""
+	if i := strings.Index(after, "\n"); i >= 0 {
+		firstLine, rest = after[:i], after[i+1:]
+	}
+
+	cmd := &Command{Action: actionRun}
+	tokens := strings.Fields(firstLine)
+	if len(tokens) > 0 {
+		switch action := strings.ToLower(tokens[0]); action {
+		case actionRun, actionRetry, actionCancel, actionStatus, actionContinue:
+			cmd.Action = action
+			tokens = tokens[1:]
+		}
+	}
+
+	var words []string
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(token, "--"), "=")
+		if !strings.HasPrefix(token, "--") || !knownFlags[name] {
+			// Tasks may mention options such as "--verbose" themselves
+			words = append(words, token)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(tokens) {
+				return nil, fmt.Errorf("flag --%s requires a value", name)
+			}
+			i++
+			value = tokens[i]
+		}
+
+		switch name {
+		case "max-turns":
+			turns, err := strconv.Atoi(value)
+			if err != nil || turns < 1 || turns > maxAllowedTurns {
+				return nil, fmt.Errorf("invalid --max-turns %q (expected 1-%d)", value, maxAllowedTurns)
+			}
+			cmd.MaxTurns = turns
+		case "ref":
+			if strings.HasPrefix(value, "-") || strings.ContainsAny(value, " \t") {
+				return nil, fmt.Errorf("invalid --ref %q", value)
+			}
+			cmd.Ref = value
+		case "repo":
+			if !repoFlagRegex.MatchString(value) {
+				return nil, fmt.Errorf("invalid --repo %q (expected owner/name)", value)
+			}
+			cmd.Repository = value
+		}
+	}
+
+	// Keep any text before the mention as part of the task, as plain
+	// "@claude" requests always have
+	task := strings.TrimSpace(before) + "\n" + strings.Join(words, " ") + "\n" + rest
+	cmd.Task = strings.TrimSpace(mentionRegex.ReplaceAllString(task, ""))
+
+	switch cmd.Action {
+	case actionRun, actionContinue:
+		if cmd.Task == "" {
+			return nil, fmt.Errorf("%s requires a task description", cmd.Action)
+		}
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import "testing"
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    Command
+		wantErr bool
+	}{
+		{
+			name: "plain mention runs the text",
+			text: "@claude fix the bug",
+			want: Command{Action: actionRun, Task: "fix the bug"},
+		},
+		{
+			name: "claude-code spelling with run and flags",
+			text: "@claude-code run add tests --max-turns 5",
+			want: Command{Action: actionRun, Task: "add tests", MaxTurns: 5},
+		},
+		{
+			name: "subcommand is case-insensitive",
+			text: "@CLAUDE Status",
+			want: Command{Action: actionStatus},
+		},
+		{
+			name: "retry",
+			text: "@claude retry",
+			want: Command{Action: actionRetry},
+		},
+		{
+			name: "cancel",
+			text: "@claude cancel",
+			want: Command{Action: actionCancel},
+		},
+		{
+			name: "continue with follow-up",
+			text: "@claude continue also update the docs",
+			want: Command{Action: actionContinue, Task: "also update the docs"},
+		},
+		{
+			name: "subcommand must directly follow the mention",
+			text: "@claude please retry",
+			want: Command{Action: actionRun, Task: "please retry"},
+		},
+		{
+			name: "text around the mention is kept and flags come from its line",
+			text: "please look\n@claude fix it --repo org/app --ref=main\nmore details",
+			want: Command{Action: actionRun, Task: "please look\nfix it\nmore details", Repository: "org/app", Ref: "main"},
+		},
+		{
+			name: "later mentions are removed from the task",
+			text: "@claude do a thing\n@claude extra",
+			want: Command{Action: actionRun, Task: "do a thing\nextra"},
+		},
+		{
+			name: "flags on later lines are part of the task",
+			text: "@claude do a thing\n--max-turns 3",
+			want: Command{Action: actionRun, Task: "do a thing\n--max-turns 3"},
+		},
+		{
+			name: "unknown flags are part of the task",
+			text: "@claude add a --verbose option to the CLI --max-turns 5",
+			want: Command{Action: actionRun, Task: "add a --verbose option to the CLI", MaxTurns: 5},
+		},
+		{
+			name: "unknown flag does not take a value",
+			text: "@claude document --dry-run=true and --force",
+			want: Command{Action: actionRun, Task: "document --dry-run=true and --force"},
+		},
+		{name: "no mention", text: "mail foo@claude.ai", wantErr: true},
+		{name: "run without task", text: "@claude run", wantErr: true},
+		{name: "continue without follow-up", text: "@claude continue", wantErr: true},
+		{name: "max-turns below range", text: "@claude x --max-turns 0", wantErr: true},
+		{name: "max-turns above range", text: "@claude x --max-turns=101", wantErr: true},
+		{name: "max-turns not a number", text: "@claude x --max-turns many", wantErr: true},
+		{name: "flag without value", text: "@claude x --ref", wantErr: true},
+		{name: "invalid repo", text: "@claude x --repo app", wantErr: true},
+		{name: "ref looks like a flag", text: "@claude x --ref=-f", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCommand(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseCommand(%q) = %+v, want error", tt.text, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCommand(%q) failed: %v", tt.text, err)
+			}
+			if *got != tt.want {
+				t.Errorf("parseCommand(%q) = %+v, want %+v", tt.text, *got, tt.want)
+			}
+		})
+	}
+}
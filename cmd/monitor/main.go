@@ -5,29 +5,70 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/oauth2"
 	"github.com/claude-automation/pkg/kubernetes"
+	"github.com/claude-automation/pkg/orchestrator"
+)
+
+// Monitor modes selectable with MONITOR_MODE
+const (
+	modePolling = "polling"
+	modeWebhook = "webhook"
 )
 
 type IssueMonitor struct {
-	client       *github.Client
-	owner        string
-	repo         string
-	pollInterval time.Duration
-	lastChecked  time.Time
-	podManager   *kubernetes.PodManager
+	client        *github.Client
+	selfLogin     string // account of GITHUB_TOKEN; its own comments are ignored
+	repositories  *repositorySet
+	mode          string
+	pollInterval  time.Duration
+	catchUpWindow time.Duration
+	cursors       map[string]time.Time // owner/repo -> last checked
+	cursorRetries map[string]int       // owner/repo -> scans that kept the cursor
+	listenAddr    string
+	webhookSecret []byte
+	podManager    *kubernetes.PodManager
+	state         *StateStore
+	authorizer    *Authorizer
+	metrics       *Metrics
+	rateMu        sync.Mutex
+	lastRate      github.Rate
+	queue         *workQueue
+	drainTimeout  time.Duration
+	orchestrator  *orchestrator.Client // runs the tasks
 }
 
+// maxCursorRetries bounds how many scans keep a repository's cursor to retry
+// comments that could not be listed
+const maxCursorRetries = 3
+
+// rerunLabel forces an issue's @claude mention to be processed again
+const rerunLabel = "claude-rerun"
+
+// cancelLabel cancels an issue's running and queued tasks
+const cancelLabel = "claude-cancel"
+
+// IssueRequest is a task requested through a @claude mention
 type IssueRequest struct {
-	IssueNumber int
-	Task        string
-	Repository  string
+	IssueNumber      int      `json:"issue_number"`
+	Task             string   `json:"task"`
+	Repository       string   `json:"repository"`        // target repository
+	SourceRepository string   `json:"source_repository"` // repository where the mention occurred
+	Ref              string   `json:"ref,omitempty"`     // branch, tag or commit; default branch when empty
+	MaxTurns         int      `json:"max_turns"`
+	RequestedBy      string   `json:"requested_by,omitempty"`
+	FollowUp         string   `json:"follow_up,omitempty"` // prompt for a resumed session; Task is used when none is stored
+	Labels           []string `json:"labels,omitempty"`    // issue labels; select repo-mapping rules
 }
 
 func NewIssueMonitor() (*IssueMonitor, error) {
@@ -45,6 +86,12 @@ func NewIssueMonitor() (*IssueMonitor, error) {
 		return nil, fmt.Errorf("GITHUB_TOKEN not set")
 	}
 
+	// The orchestrator service runs the tasks; the monitor only submits them
+	orchestratorURL := os.Getenv("ORCHESTRATOR_URL")
+	if orchestratorURL == "" {
+		return nil, fmt.Errorf("ORCHESTRATOR_URL not set")
+	}
+
 	owner := os.Getenv("GITHUB_OWNER")
 	if owner == "" {
 		owner = "worldscandy"
@@ -55,6 +102,77 @@ func NewIssueMonitor() (*IssueMonitor, error) {
 		repo = "claude-automation"
 	}
 
+	// GITHUB_REPOS may list several repositories or org patterns; the single
+	// GITHUB_OWNER/GITHUB_REPO pair is used when it is not set
+	repositories, err := newRepositorySet(owner + "/" + repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Polling remains the default; webhook mode needs a shared secret
+	mode := os.Getenv("MONITOR_MODE")
+	if mode == "" {
+		mode = modePolling
+	}
+	if mode != modePolling && mode != modeWebhook {
+		return nil, fmt.Errorf("invalid MONITOR_MODE %q (expected %q or %q)", mode, modePolling, modeWebhook)
+	}
+
+	webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if mode == modeWebhook && webhookSecret == "" {
+		return nil, fmt.Errorf("GITHUB_WEBHOOK_SECRET not set (required in webhook mode)")
+	}
+
+	pollInterval := 30 * time.Second
+	if value := os.Getenv("POLLING_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid POLLING_INTERVAL %q: %w", value, err)
+		}
+		pollInterval = interval
+	}
+
+	listenAddr := os.Getenv("MONITOR_LISTEN_ADDR")
+	if listenAddr == "" {
+		listenAddr = ":8080"
+	}
+
+	// Mentions posted while the monitor was down are picked up within this window
+	catchUpWindow := 24 * time.Hour
+	if value := os.Getenv("MONITOR_CATCHUP_WINDOW"); value != "" {
+		window, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MONITOR_CATCHUP_WINDOW %q: %w", value, err)
+		}
+		catchUpWindow = window
+	}
+
+	// Bound how many tasks run at once
+	maxWorkers, err := positiveIntEnv("MAX_WORKERS", 5)
+	if err != nil {
+		return nil, err
+	}
+	maxWorkersPerRepo, err := positiveIntEnv("MAX_WORKERS_PER_REPO", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Running tasks get this long to finish on shutdown before they are cancelled
+	drainTimeout := 10 * time.Minute
+	if value := os.Getenv("DRAIN_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DRAIN_TIMEOUT %q: %w", value, err)
+		}
+		drainTimeout = timeout
+	}
+
+	// Decide who may trigger tasks
+	policy, err := loadAuthorizationPolicy()
+	if err != nil {
+		return nil, err
+	}
+
 	// Create GitHub client with OAuth2 token
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
@@ -63,7 +181,14 @@ func NewIssueMonitor() (*IssueMonitor, error) {
 	tc := oauth2.NewClient(ctx, ts)
 	client := github.NewClient(tc)
 
-	// Initialize Kubernetes Pod Manager
+	// The monitor's own replies quote commands such as "@claude retry", so
+	// comments from the token's account must never be treated as requests
+	self, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authenticated GitHub user: %w", err)
+	}
+
+	// The Kubernetes client keeps the monitor state in a ConfigMap
 	namespace := os.Getenv("NAMESPACE")
 	if namespace == "" {
 		namespace = "claude-automation"
@@ -74,25 +199,59 @@ func NewIssueMonitor() (*IssueMonitor, error) {
 		return nil, fmt.Errorf("failed to create pod manager: %w", err)
 	}
 
-	// Setup ServiceAccount and RBAC
-	if err := podManager.SetupServiceAccount(ctx); err != nil {
-		log.Printf("Warning: Failed to setup ServiceAccount: %v", err)
+	// Load processed triggers and the polling cursor
+	backend, err := newStateBackend(podManager)
+	if err != nil {
+		return nil, err
+	}
+	state, err := NewStateStore(backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load monitor state: %w", err)
 	}
 
-	return &IssueMonitor{
-		client:       client,
-		owner:        owner,
-		repo:         repo,
-		pollInterval: 30 * time.Second,
-		lastChecked:  time.Now(),
-		podManager:   podManager,
-	}, nil
+	metrics := NewMetrics()
+	monitor := &IssueMonitor{
+		client:        client,
+		selfLogin:     self.GetLogin(),
+		repositories:  repositories,
+		mode:          mode,
+		pollInterval:  pollInterval,
+		catchUpWindow: catchUpWindow,
+		cursors:       make(map[string]time.Time),
+		cursorRetries: make(map[string]int),
+		listenAddr:    listenAddr,
+		webhookSecret: []byte(webhookSecret),
+		podManager:    podManager,
+		state:         state,
+		authorizer:    NewAuthorizer(client, policy),
+		metrics:       metrics,
+		drainTimeout:  drainTimeout,
+		orchestrator:  orchestrator.NewClient(orchestratorURL, os.Getenv("ORCHESTRATOR_API_TOKEN")),
+	}
+	log.Printf("Tasks are submitted to the orchestrator at %s", orchestratorURL)
+	monitor.queue = newWorkQueue(maxWorkers, maxWorkersPerRepo, metrics, monitor.runQueuedTask)
+	return monitor, nil
 }
 
 func (m *IssueMonitor) Start(ctx context.Context) error {
-	log.Printf("Starting GitHub Issue Monitor for %s/%s", m.owner, m.repo)
+	log.Printf("Starting GitHub Issue Monitor for %s (mode: %s)", m.repositories, m.mode)
+
+	m.startHTTPServer(ctx)
+
+	if m.mode == modeWebhook {
+		// Catch up on mentions missed while no deliveries were received, then
+		// leave the rest to the HTTP server until shutdown
+		if err := m.checkIssues(ctx); err != nil {
+			log.Printf("Catch-up check failed: %v", err)
+		}
+		<-ctx.Done()
+		log.Println("Shutting down issue monitor")
+		m.drain()
+		return ctx.Err()
+	}
+
 	log.Printf("Polling interval: %v", m.pollInterval)
-	
+
 	// Initial check
 	if err := m.checkIssues(ctx); err != nil {
 		log.Printf("Initial check failed: %v", err)
@@ -106,6 +265,7 @@ func (m *IssueMonitor) Start(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			log.Println("Shutting down issue monitor")
+			m.drain()
 			return ctx.Err()
 		case <-ticker.C:
 			if err := m.checkIssues(ctx); err != nil {
@@ -115,125 +275,271 @@ func (m *IssueMonitor) Start(ctx context.Context) error {
 	}
 }
 
+// drain lets running tasks finish and tells issues whose queued tasks were
+// dropped that they can be retried
+func (m *IssueMonitor) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for _, task := range m.queue.Drain(m.drainTimeout) {
+		m.postComment(ctx, task.repo, task.request.IssueNumber,
+			"⚠️ **モニターの停止により、待機中のタスクは実行されませんでした**\n\n再起動後に `@claude retry` で再実行できます。")
+	}
+}
+
 func (m *IssueMonitor) checkIssues(ctx context.Context) error {
-	// List issues updated since last check
-	opts := &github.IssueListByRepoOptions{
-		State:     "open",
-		Sort:      "updated",
-		Direction: "desc",
-		Since:     m.lastChecked,
-		ListOptions: github.ListOptions{
-			PerPage: 100,
-		},
+	repos, err := m.watchedRepositories(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to resolve watched repositories: %w", err)
+	}
+
+	scanned := 0
+	for _, repo := range repos {
+		count, err := m.checkRepository(ctx, repo)
+		if err != nil {
+			log.Printf("Error checking %s: %v", repo.FullName(), err)
+			continue
+		}
+		scanned += count
 	}
 
-	issues, _, err := m.client.Issues.ListByRepo(ctx, m.owner, m.repo, opts)
+	m.metrics.SetGauge("claude_monitor_issues_scanned",
+		"Number of updated issues returned by the last scan", float64(scanned))
+	m.logRate()
+	return nil
+}
+
+// cursor returns the time the repository was last checked, resuming from the
+// persisted state the first time a repository is scanned
+func (m *IssueMonitor) cursor(repo watchedRepository) time.Time {
+	cursor, ok := m.cursors[repo.FullName()]
+	if !ok {
+		cursor = m.state.ResumeCursor(repo.FullName(), m.catchUpWindow)
+		m.cursors[repo.FullName()] = cursor
+		log.Printf("Resuming %s from %s", repo.FullName(), cursor.Format(time.RFC3339))
+	}
+	return cursor
+}
+
+// checkRepository scans one repository for new mentions and returns the
+// number of updated issues
+func (m *IssueMonitor) checkRepository(ctx context.Context, repo watchedRepository) (int, error) {
+	// Anything updated while this scan runs is picked up by the next one
+	scanStarted := time.Now()
+	since := m.cursor(repo)
+
+	// List issues updated since last check
+	issues, err := m.listUpdatedIssues(ctx, repo, since)
 	if err != nil {
-		return fmt.Errorf("failed to list issues: %w", err)
+		return 0, fmt.Errorf("failed to list issues: %w", err)
 	}
 
 	// Check each issue for @claude mentions
+	var failed []string
 	for _, issue := range issues {
+		if hasLabel(issue, cancelLabel) {
+			m.handleCancelLabel(ctx, repo, issue.GetNumber(), "")
+		}
 		if issue.Body == nil {
 			continue
 		}
 
 		// Check issue body for @claude mention
 		if m.hasClauldeMention(*issue.Body) {
-			log.Printf("Found @claude mention in issue #%d: %s", *issue.Number, *issue.Title)
-			m.handleIssue(ctx, issue)
+			log.Printf("Found @claude mention in %s#%d: %s", repo.FullName(), *issue.Number, *issue.Title)
+			m.handleIssue(ctx, repo, issue)
 		}
 
 		// Check recent comments
-		if err := m.checkIssueComments(ctx, issue); err != nil {
-			log.Printf("Error checking comments for issue #%d: %v", *issue.Number, err)
+		if err := m.checkIssueComments(ctx, repo, issue, since); err != nil {
+			log.Printf("Error checking comments for %s#%d: %v", repo.FullName(), *issue.Number, err)
+			failed = append(failed, fmt.Sprintf("#%d", *issue.Number))
 		}
 	}
 
-	m.lastChecked = time.Now()
-	return nil
-}
-
-func (m *IssueMonitor) checkIssueComments(ctx context.Context, issue *github.Issue) error {
-	opts := &github.IssueListCommentsOptions{
-		Since: &m.lastChecked,
-		ListOptions: github.ListOptions{
-			PerPage: 100,
-		},
+	// Keep the cursor so the next scans retry the comments that were missed;
+	// mentions already handled are skipped by their recorded trigger. An
+	// issue whose comments keep failing must not hold back the others, so
+	// the cursor moves on after maxCursorRetries scans
+	if len(failed) > 0 {
+		m.cursorRetries[repo.FullName()]++
+		if retries := m.cursorRetries[repo.FullName()]; retries <= maxCursorRetries {
+			return len(issues), fmt.Errorf("failed to check comments of %s, keeping cursor at %v (retry %d of %d)",
+				strings.Join(failed, ", "), since, retries, maxCursorRetries)
+		}
+		log.Printf("Giving up on comments of %s in %s after %d retries, advancing cursor",
+			strings.Join(failed, ", "), repo.FullName(), maxCursorRetries)
+		m.metrics.AddCounter("claude_monitor_skipped_comment_checks_total",
+			"Number of issues whose comments were skipped after repeated failures", float64(len(failed)))
 	}
+	delete(m.cursorRetries, repo.FullName())
+	m.cursors[repo.FullName()] = scanStarted
+	// Without updated issues a restart would rescan an empty range, so the
+	// saved cursor only moves past issues that were handled
+	if len(issues) > 0 {
+		m.state.AdvanceCursor(repo.FullName(), scanStarted)
+	}
+	return len(issues), nil
+}
 
-	comments, _, err := m.client.Issues.ListComments(ctx, m.owner, m.repo, *issue.Number, opts)
+func (m *IssueMonitor) checkIssueComments(ctx context.Context, repo watchedRepository, issue *github.Issue, since time.Time) error {
+	comments, err := m.listIssueComments(ctx, repo, *issue.Number, since)
 	if err != nil {
 		return err
 	}
 
+	lastSeen := m.state.LastCommentID(repo.FullName(), *issue.Number)
 	for _, comment := range comments {
 		if comment.Body == nil {
 			continue
 		}
 
+		// Comments at the Since boundary are returned again; skip the ones
+		// already seen unless they were edited after the cursor
+		if comment.GetID() <= lastSeen && !comment.GetUpdatedAt().After(since) {
+			continue
+		}
+		m.state.RecordCommentID(repo.FullName(), *issue.Number, comment.GetID())
+
 		if m.hasClauldeMention(*comment.Body) {
-			log.Printf("Found @claude mention in comment on issue #%d", *issue.Number)
-			m.handleIssueComment(ctx, issue, comment)
+			log.Printf("Found @claude mention in comment on %s#%d", repo.FullName(), *issue.Number)
+			m.handleIssueComment(ctx, repo, issue, comment)
 		}
 	}
 
 	return nil
 }
 
+// postComment posts a comment to an issue in the repository where the mention occurred
+func (m *IssueMonitor) postComment(ctx context.Context, repo watchedRepository, issueNumber int, body string) {
+	comment := &github.IssueComment{Body: &body}
+	err := m.callGitHub(ctx, func() (*github.Response, error) {
+		_, resp, err := m.client.Issues.CreateComment(ctx, repo.Owner, repo.Name, issueNumber, comment)
+		return resp, err
+	})
+	if err != nil {
+		log.Printf("Failed to post comment to %s#%d: %v", repo.FullName(), issueNumber, err)
+	}
+}
+
+// createComment posts a comment and returns its ID so it can be edited later
+func (m *IssueMonitor) createComment(ctx context.Context, repo watchedRepository, issueNumber int, body string) int64 {
+	var created *github.IssueComment
+	comment := &github.IssueComment{Body: &body}
+	err := m.callGitHub(ctx, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		created, resp, err = m.client.Issues.CreateComment(ctx, repo.Owner, repo.Name, issueNumber, comment)
+		return resp, err
+	})
+	if err != nil {
+		log.Printf("Failed to post comment to %s#%d: %v", repo.FullName(), issueNumber, err)
+		return 0
+	}
+	return created.GetID()
+}
+
+// editComment replaces the body of a comment posted by createComment
+func (m *IssueMonitor) editComment(ctx context.Context, repo watchedRepository, commentID int64, body string) {
+	if commentID == 0 {
+		return
+	}
+	comment := &github.IssueComment{Body: &body}
+	err := m.callGitHub(ctx, func() (*github.Response, error) {
+		_, resp, err := m.client.Issues.EditComment(ctx, repo.Owner, repo.Name, commentID, comment)
+		return resp, err
+	})
+	if err != nil {
+		log.Printf("Failed to edit comment %d on %s: %v", commentID, repo.FullName(), err)
+	}
+}
+
 func (m *IssueMonitor) hasClauldeMention(text string) bool {
 	// Check for @claude mention (case insensitive, not in email addresses)
 	mentionRegex := regexp.MustCompile(`(?i)(?:^|[^a-zA-Z0-9.])@claude\b`)
 	return mentionRegex.MatchString(text)
 }
 
-func (m *IssueMonitor) handleIssue(ctx context.Context, issue *github.Issue) {
-	// Extract task from issue
-	task := m.extractTask(issue)
-	
-	// Detect target repository
-	repository := m.detectTargetRepository(issue, task)
-	
-	log.Printf("Processing task from issue #%d", *issue.Number)
-	log.Printf("Task: %s", task)
-	log.Printf("Target repository: %s", repository)
+func (m *IssueMonitor) handleIssue(ctx context.Context, repo watchedRepository, issue *github.Issue) {
+	// Skip mentions that were already processed unless a re-run was requested
+	force := hasLabel(issue, rerunLabel)
+	if !m.state.ClaimTrigger(repo.FullName(), *issue.Number, 0, *issue.Body, force) {
+		log.Printf("Skipping already processed mention in %s#%d", repo.FullName(), *issue.Number)
+		return
+	}
+	if force {
+		log.Printf("Re-running %s#%d (%s label)", repo.FullName(), *issue.Number, rerunLabel)
+		err := m.callGitHub(ctx, func() (*github.Response, error) {
+			return m.client.Issues.RemoveLabelForIssue(ctx, repo.Owner, repo.Name, *issue.Number, rerunLabel)
+		})
+		if err != nil {
+			log.Printf("Warning: failed to remove %s label from %s#%d: %v", rerunLabel, repo.FullName(), *issue.Number, err)
+		}
+	}
+
+	// Only authorized users may run tasks with the worker credentials
+	if !m.authorizeRequest(ctx, repo, *issue.Number, issue.GetUser(), issue.GetAuthorAssociation()) {
+		return
+	}
 
-	// Trigger orchestrator to handle the task
-	m.triggerOrchestrator(ctx, *issue.Number, task, repository)
+	// Parse the subcommand and dispatch it
+	m.dispatchCommand(ctx, repo, issue, *issue.Body, issue.GetUser().GetLogin())
 }
 
-func (m *IssueMonitor) handleIssueComment(ctx context.Context, issue *github.Issue, comment *github.IssueComment) {
-	// Extract task from comment
-	task := m.extractTaskFromComment(comment)
-	
-	// Detect target repository
-	repository := m.detectTargetRepository(issue, task)
-	
-	log.Printf("Processing task from comment on issue #%d", *issue.Number)
-	log.Printf("Task: %s", task)
-	log.Printf("Target repository: %s", repository)
+func (m *IssueMonitor) handleIssueComment(ctx context.Context, repo watchedRepository, issue *github.Issue, comment *github.IssueComment) {
+	if strings.EqualFold(comment.GetUser().GetLogin(), m.selfLogin) {
+		return
+	}
 
-	// Trigger orchestrator to handle the task
-	m.triggerOrchestrator(ctx, *issue.Number, task, repository)
+	// Skip comments that were already processed
+	if !m.state.ClaimTrigger(repo.FullName(), *issue.Number, comment.GetID(), *comment.Body, false) {
+		log.Printf("Skipping already processed comment %d on %s#%d", comment.GetID(), repo.FullName(), *issue.Number)
+		return
+	}
+
+	// Only authorized users may run tasks with the worker credentials
+	if !m.authorizeRequest(ctx, repo, *issue.Number, comment.GetUser(), comment.GetAuthorAssociation()) {
+		return
+	}
+
+	// Parse the subcommand and dispatch it
+	m.dispatchCommand(ctx, repo, issue, *comment.Body, comment.GetUser().GetLogin())
 }
 
-func (m *IssueMonitor) extractTask(issue *github.Issue) string {
-	// Remove @claude mention and extract the actual task
-	text := *issue.Body
-	mentionRegex := regexp.MustCompile(`(?i)@claude\s*`)
-	task := mentionRegex.ReplaceAllString(text, "")
-	return strings.TrimSpace(task)
+// positiveIntEnv reads a positive integer from the environment
+func positiveIntEnv(name string, defaultValue int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s %q (expected a positive integer)", name, value)
+	}
+	return n, nil
 }
 
-func (m *IssueMonitor) extractTaskFromComment(comment *github.IssueComment) string {
-	text := *comment.Body
-	mentionRegex := regexp.MustCompile(`(?i)@claude\s*`)
-	task := mentionRegex.ReplaceAllString(text, "")
-	return strings.TrimSpace(task)
+// hasLabel reports whether the issue carries the given label
+func hasLabel(issue *github.Issue, name string) bool {
+	for _, label := range issue.Labels {
+		if strings.EqualFold(label.GetName(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// issueLabels returns the label names of an issue
+func issueLabels(issue *github.Issue) []string {
+	var names []string
+	for _, label := range issue.Labels {
+		names = append(names, label.GetName())
+	}
+	return names
 }
 
 // detectTargetRepository determines which repository the task should target
-func (m *IssueMonitor) detectTargetRepository(issue *github.Issue, task string) string {
+func (m *IssueMonitor) detectTargetRepository(repo watchedRepository, issue *github.Issue, task string) string {
 	// Priority 1: Look for explicit repository mention in task
 	repoRegex := regexp.MustCompile(`(?i)(?:repository|repo):\s*([a-zA-Z0-9._-]+/[a-zA-Z0-9._-]+)`)
 	if matches := repoRegex.FindStringSubmatch(task); len(matches) > 1 {
@@ -272,108 +578,40 @@ func (m *IssueMonitor) detectTargetRepository(issue *github.Issue, task string)
 		}
 	}
 	
-	// Priority 5: Use current repository as default
-	return fmt.Sprintf("%s/%s", m.owner, m.repo)
+	// Priority 5: Use the repository where the mention occurred
+	return repo.FullName()
 }
 
-// triggerOrchestrator communicates with orchestrator to process the task
-func (m *IssueMonitor) triggerOrchestrator(ctx context.Context, issueNumber int, task string, repository string) {
-	// In a real implementation, this would communicate with the orchestrator
-	// via HTTP, gRPC, or message queue. For now, we'll simulate it.
-	
-	log.Printf("Triggering orchestrator for issue #%d (repository: %s)", issueNumber, repository)
-	
-	// For this implementation, we'll spawn the orchestrator process
-	// In production, the orchestrator would be a separate service
-	go m.executeOrchestratorTask(ctx, issueNumber, task, repository)
+// triggerOrchestrator runs the task through the orchestrator service, which
+// creates the worker and posts the progress and result comments
+func (m *IssueMonitor) triggerOrchestrator(ctx context.Context, repo watchedRepository, request *IssueRequest) {
+	log.Printf("Triggering orchestrator for %s#%d (repository: %s)", repo.FullName(), request.IssueNumber, request.Repository)
+	m.runRemoteTask(ctx, repo, request)
 }
 
-func (m *IssueMonitor) executeOrchestratorTask(ctx context.Context, issueNumber int, task string, repository string) {
-	// Create worker pod instead of Docker container
-	
-	// Default configuration for Claude workers
-	config := &kubernetes.RepositoryConfig{
-		Image:     "worldscandy/claude-automation:latest",
-		Workspace: "/workspace",
-		Env: []string{
-			"CLAUDE_API_KEY=" + os.Getenv("CLAUDE_API_KEY"),
-			"GITHUB_TOKEN=" + os.Getenv("GITHUB_TOKEN"),
-		},
-		Commands: map[string]string{
-			"claude": "claude",
-		},
-	}
-
-	// Create worker pod
-	workerPod, err := m.podManager.CreateWorkerPod(ctx, issueNumber, repository, config)
-	if err != nil {
-		log.Printf("Failed to create worker pod for issue #%d: %v", issueNumber, err)
-		
-		// Post error to issue
-		errorBody := fmt.Sprintf("❌ **Kubernetes Pod作成に失敗しました**\n\n```\n%s\n```", err.Error())
-		comment := &github.IssueComment{Body: &errorBody}
-		m.client.Issues.CreateComment(ctx, m.owner, m.repo, issueNumber, comment)
-		return
-	}
-
-	log.Printf("Created worker pod %s for issue #%d", workerPod.PodName, issueNumber)
-
-	// Post progress update to issue
-	progressBody := fmt.Sprintf("🚀 **タスク処理を開始しました**\n\nIssue #%d の処理を Kubernetes Pod `%s` で実行中です...", 
-		issueNumber, workerPod.PodName)
-	comment := &github.IssueComment{Body: &progressBody}
-	m.client.Issues.CreateComment(ctx, m.owner, m.repo, issueNumber, comment)
-
-	// Wait for pod to be ready
-	if err := m.podManager.WaitForPodReady(ctx, workerPod.PodName, 5*time.Minute); err != nil {
-		log.Printf("Pod %s failed to become ready: %v", workerPod.PodName, err)
-		
-		// Get pod logs for debugging
-		logs, _ := m.podManager.GetPodLogs(ctx, workerPod.PodName)
-		
-		errorBody := fmt.Sprintf("❌ **Pod起動に失敗しました**\n\n```\n%s\n```\n\n**Pod Logs:**\n```\n%s\n```", 
-			err.Error(), logs)
-		comment := &github.IssueComment{Body: &errorBody}
-		m.client.Issues.CreateComment(ctx, m.owner, m.repo, issueNumber, comment)
-		
-		// Cleanup failed pod
-		m.podManager.DeleteWorkerPod(ctx, workerPod.PodName)
-		return
+// reportCancelled posts a cancellation notice, followed by details such as
+// the partial output, if the task context was cancelled and reports whether
+// it did
+func (m *IssueMonitor) reportCancelled(ctx context.Context, repo watchedRepository, request *IssueRequest, details string) bool {
+	if ctx.Err() == nil {
+		return false
 	}
 
-	log.Printf("Pod %s is ready, executing Claude CLI task", workerPod.PodName)
-
-	// Execute Claude CLI task in the pod
-	claudeCommand := fmt.Sprintf("claude --print --max-turns 10 --verbose '%s'", task)
-	output, err := m.podManager.ExecuteInPod(ctx, workerPod.PodName, claudeCommand)
-	
-	if err != nil {
-		log.Printf("Claude CLI execution failed in pod %s: %v", workerPod.PodName, err)
-		
-		// Get pod logs for debugging
-		logs, _ := m.podManager.GetPodLogs(ctx, workerPod.PodName)
-		
-		errorBody := fmt.Sprintf("❌ **Claude CLI実行に失敗しました**\n\n```\n%s\n```\n\n**Pod Logs:**\n```\n%s\n```", 
-			err.Error(), logs)
-		comment := &github.IssueComment{Body: &errorBody}
-		m.client.Issues.CreateComment(ctx, m.owner, m.repo, issueNumber, comment)
+	body := "🛑 **タスクをキャンセルしました**"
+	if by := m.cancelledBy(repo, request.IssueNumber); by != "" {
+		body += fmt.Sprintf(" (@%s によるキャンセル)\n", by)
 	} else {
-		// Post successful result
-		resultBody := fmt.Sprintf("✅ **タスクが完了しました**\n\n**実行結果:**\n```\n%s\n```", output)
-		comment := &github.IssueComment{Body: &resultBody}
-		m.client.Issues.CreateComment(ctx, m.owner, m.repo, issueNumber, comment)
-		
-		log.Printf("Task completed successfully for issue #%d", issueNumber)
-	}
-
-	// Cleanup: Delete the worker pod after task completion
-	if err := m.podManager.DeleteWorkerPod(ctx, workerPod.PodName); err != nil {
-		log.Printf("Warning: Failed to cleanup pod %s: %v", workerPod.PodName, err)
+		body += "\n\nモニターの停止により中断されました。`@claude retry` で再実行できます。\n"
 	}
+	body += details
+	log.Printf("Task for %s#%d was cancelled", repo.FullName(), request.IssueNumber)
+	m.postComment(context.WithoutCancel(ctx), repo, request.IssueNumber, body)
+	return true
 }
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	monitor, err := NewIssueMonitor()
 	if err != nil {
@@ -381,7 +619,7 @@ func main() {
 	}
 
 	// Start monitoring
-	if err := monitor.Start(ctx); err != nil {
+	if err := monitor.Start(ctx); err != nil && err != context.Canceled {
 		log.Fatal("Monitor error:", err)
 	}
 }
\ No newline at end of file
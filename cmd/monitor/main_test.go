@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// newFailingCommentsMonitor returns a monitor for org/app whose only updated
+// issue, #1, fails every comment listing
+func newFailingCommentsMonitor(t *testing.T) *IssueMonitor {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/app/issues", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"number":1,"title":"broken","body":"no mention"}]`))
+	})
+	mux.HandleFunc("/repos/org/app/issues/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	state, err := NewStateStore(&fileStateBackend{path: filepath.Join(t.TempDir(), "state.json")})
+	if err != nil {
+		t.Fatalf("NewStateStore failed: %v", err)
+	}
+	return &IssueMonitor{
+		client:        client,
+		catchUpWindow: time.Hour,
+		cursors:       make(map[string]time.Time),
+		cursorRetries: make(map[string]int),
+		state:         state,
+		metrics:       NewMetrics(),
+	}
+}
+
+func TestCheckRepositoryRetriesFailedCommentsAFewTimes(t *testing.T) {
+	m := newFailingCommentsMonitor(t)
+	repo, err := parseRepository("org/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	since := m.cursor(repo)
+	for i := 1; i <= maxCursorRetries; i++ {
+		if _, err := m.checkRepository(context.Background(), repo); err == nil {
+			t.Fatalf("scan %d: checkRepository succeeded, want error for the failed comments", i)
+		}
+		if got := m.cursor(repo); !got.Equal(since) {
+			t.Fatalf("scan %d: cursor moved to %v, want it kept at %v", i, got, since)
+		}
+	}
+
+	if _, err := m.checkRepository(context.Background(), repo); err != nil {
+		t.Fatalf("checkRepository after %d retries failed: %v", maxCursorRetries, err)
+	}
+	if got := m.cursor(repo); !got.After(since) {
+		t.Errorf("cursor = %v after %d retries, want it advanced past %v", got, maxCursorRetries, since)
+	}
+	if retries := m.cursorRetries[repo.FullName()]; retries != 0 {
+		t.Errorf("cursorRetries = %d after the cursor moved, want 0", retries)
+	}
+}
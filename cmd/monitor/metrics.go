@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// metric is a single Prometheus gauge or counter
+type metric struct {
+	help  string
+	kind  string // "gauge" or "counter"
+	value float64
+}
+
+// Metrics is a minimal registry exposed in the Prometheus text format on /metrics
+type Metrics struct {
+	mu      sync.Mutex
+	metrics map[string]*metric
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{metrics: make(map[string]*metric)}
+}
+
+// SetGauge sets a gauge to the given value
+func (mr *Metrics) SetGauge(name, help string, value float64) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.get(name, help, "gauge").value = value
+}
+
+// AddCounter increments a counter by delta
+func (mr *Metrics) AddCounter(name, help string, delta float64) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	mr.get(name, help, "counter").value += delta
+}
+
+func (mr *Metrics) get(name, help, kind string) *metric {
+	m, ok := mr.metrics[name]
+	if !ok {
+		m = &metric{help: help, kind: kind}
+		mr.metrics[name] = m
+	}
+	return m
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format
+func (mr *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mr.mu.Lock()
+	names := make([]string, 0, len(mr.metrics))
+	for name := range mr.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		m := mr.metrics[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, m.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, m.kind)
+		fmt.Fprintf(&b, "%s %g\n", name, m.value)
+	}
+	mr.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
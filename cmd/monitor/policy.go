@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"gopkg.in/yaml.v2"
+)
+
+// membershipCacheTTL bounds how long org and team lookups are reused
+const membershipCacheTTL = 10 * time.Minute
+
+// defaultAllowedAssociations applies when no policy file is configured:
+// only people with write access to the repository may trigger tasks
+var defaultAllowedAssociations = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+// AuthorizationPolicy decides who may trigger @claude. Denied users are
+// always rejected; otherwise a request is accepted when any allow rule
+// matches.
+type AuthorizationPolicy struct {
+	DenyUsers         []string `yaml:"deny_users"`
+	AllowUsers        []string `yaml:"allow_users"`
+	AllowOrgs         []string `yaml:"allow_orgs"`         // members of these organizations
+	AllowTeams        []string `yaml:"allow_teams"`        // org/team-slug
+	AllowAssociations []string `yaml:"allow_associations"` // OWNER, MEMBER, COLLABORATOR, CONTRIBUTOR, ...
+	RejectionMessage  string   `yaml:"rejection_message"`  // optional override of the reply
+}
+
+// loadAuthorizationPolicy reads the policy from MONITOR_POLICY_FILE, falling
+// back to the default association-based policy
+func loadAuthorizationPolicy() (*AuthorizationPolicy, error) {
+	policyPath := os.Getenv("MONITOR_POLICY_FILE")
+	if policyPath == "" {
+		log.Printf("MONITOR_POLICY_FILE not set, allowing %s", strings.Join(defaultAllowedAssociations, ", "))
+		return &AuthorizationPolicy{AllowAssociations: defaultAllowedAssociations}, nil
+	}
+
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorization policy: %w", err)
+	}
+
+	var policy AuthorizationPolicy
+	if err := yaml.UnmarshalStrict(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse authorization policy: %w", err)
+	}
+
+	for _, team := range policy.AllowTeams {
+		if _, _, ok := strings.Cut(team, "/"); !ok {
+			return nil, fmt.Errorf("invalid team %q in authorization policy (expected org/team-slug)", team)
+		}
+	}
+	if len(policy.AllowUsers)+len(policy.AllowOrgs)+len(policy.AllowTeams)+len(policy.AllowAssociations) == 0 {
+		return nil, fmt.Errorf("authorization policy %s has no allow rules", policyPath)
+	}
+
+	log.Printf("Loaded authorization policy from %s", policyPath)
+	return &policy, nil
+}
+
+// Authorizer evaluates the authorization policy, caching membership lookups
+type Authorizer struct {
+	client *github.Client
+	policy *AuthorizationPolicy
+
+	mu    sync.Mutex
+	cache map[string]membershipEntry
+}
+
+type membershipEntry struct {
+	member    bool
+	checkedAt time.Time
+}
+
+// NewAuthorizer creates an authorizer for the given policy
+func NewAuthorizer(client *github.Client, policy *AuthorizationPolicy) *Authorizer {
+	return &Authorizer{
+		client: client,
+		policy: policy,
+		cache:  make(map[string]membershipEntry),
+	}
+}
+
+// Authorize reports whether login may trigger a task and the rule that decided it
+func (a *Authorizer) Authorize(ctx context.Context, login, association string) (bool, string) {
+	if login == "" {
+		return false, "unknown user"
+	}
+
+	if containsFold(a.policy.DenyUsers, login) {
+		return false, "user is denied"
+	}
+	if containsFold(a.policy.AllowUsers, login) {
+		return true, "user is allowed"
+	}
+	if association != "" && containsFold(a.policy.AllowAssociations, association) {
+		return true, "author association " + association
+	}
+
+	for _, org := range a.policy.AllowOrgs {
+		member, err := a.isOrgMember(ctx, org, login)
+		if err != nil {
+			log.Printf("Warning: failed to check %s membership of %s: %v", org, login, err)
+			continue
+		}
+		if member {
+			return true, "member of " + org
+		}
+	}
+
+	for _, team := range a.policy.AllowTeams {
+		member, err := a.isTeamMember(ctx, team, login)
+		if err != nil {
+			log.Printf("Warning: failed to check %s membership of %s: %v", team, login, err)
+			continue
+		}
+		if member {
+			return true, "member of " + team
+		}
+	}
+
+	return false, "no allow rule matched (author association " + association + ")"
+}
+
+// AuthorizeTarget reports whether login may run a task requested on source
+// against target, and why. Beyond the policy checked for the mention, a task
+// on another repository needs write access to it, so the monitor's token
+// cannot be used to reach repositories the requester could not change.
+func (a *Authorizer) AuthorizeTarget(ctx context.Context, login, source, target string) (bool, string) {
+	if strings.EqualFold(source, target) {
+		return true, "same repository"
+	}
+
+	owner, name, ok := strings.Cut(target, "/")
+	if !ok {
+		return false, "invalid target repository " + target
+	}
+	canWrite, err := a.cachedMembership("write:"+target+"/"+login, func() (bool, error) {
+		level, resp, err := a.client.Repositories.GetPermissionLevel(ctx, owner, name, login)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+		switch level.GetPermission() {
+		case "admin", "maintain", "write":
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		log.Printf("Warning: failed to check %s permission of %s: %v", target, login, err)
+		return false, "permission check on " + target + " failed"
+	}
+	if !canWrite {
+		return false, "no write access to " + target
+	}
+	return true, "write access to " + target
+}
+
+func (a *Authorizer) isOrgMember(ctx context.Context, org, login string) (bool, error) {
+	return a.cachedMembership("org:"+org+"/"+login, func() (bool, error) {
+		member, _, err := a.client.Organizations.IsMember(ctx, org, login)
+		return member, err
+	})
+}
+
+func (a *Authorizer) isTeamMember(ctx context.Context, team, login string) (bool, error) {
+	org, slug, _ := strings.Cut(team, "/")
+	return a.cachedMembership("team:"+team+"/"+login, func() (bool, error) {
+		membership, resp, err := a.client.Teams.GetTeamMembershipBySlug(ctx, org, slug, login)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+		return membership.GetState() == "active", nil
+	})
+}
+
+func (a *Authorizer) cachedMembership(key string, lookup func() (bool, error)) (bool, error) {
+	a.mu.Lock()
+	entry, ok := a.cache[strings.ToLower(key)]
+	a.mu.Unlock()
+	if ok && time.Since(entry.checkedAt) < membershipCacheTTL {
+		return entry.member, nil
+	}
+
+	member, err := lookup()
+	if err != nil {
+		return false, err
+	}
+
+	a.mu.Lock()
+	a.cache[strings.ToLower(key)] = membershipEntry{member: member, checkedAt: time.Now()}
+	a.mu.Unlock()
+	return member, nil
+}
+
+// authorizeRequest checks the policy for a mention and answers rejected
+// requests with a comment
+func (m *IssueMonitor) authorizeRequest(ctx context.Context, repo watchedRepository, issueNumber int, user *github.User, association string) bool {
+	login := user.GetLogin()
+	allowed, reason := m.authorizer.Authorize(ctx, login, association)
+	if allowed {
+		log.Printf("Authorized @%s on %s#%d: %s", login, repo.FullName(), issueNumber, reason)
+		return true
+	}
+
+	log.Printf("Rejected @claude request from @%s on %s#%d: %s", login, repo.FullName(), issueNumber, reason)
+	m.metrics.AddCounter("claude_monitor_rejected_requests_total",
+		"Number of @claude requests rejected by the authorization policy", 1)
+
+	body := m.authorizer.policy.RejectionMessage
+	if body == "" {
+		body = fmt.Sprintf("🙏 @%s さん、ご依頼ありがとうございます。\n\n"+
+			"申し訳ありませんが、このリポジトリでは許可されたユーザーのみが `@claude` にタスクを依頼できます。"+
+			"実行が必要な場合は、リポジトリのメンテナーに依頼してください。", login)
+	}
+	m.postComment(ctx, repo, issueNumber, body)
+	return false
+}
+
+// authorizeTarget checks that the requester may work on the target
+// repository of a task and answers rejected requests with a comment
+func (m *IssueMonitor) authorizeTarget(ctx context.Context, repo watchedRepository, request *IssueRequest) bool {
+	allowed, reason := m.authorizer.AuthorizeTarget(ctx, request.RequestedBy, repo.FullName(), request.Repository)
+	if allowed {
+		return true
+	}
+
+	log.Printf("Rejected task from @%s on %s#%d for %s: %s", request.RequestedBy, repo.FullName(), request.IssueNumber, request.Repository, reason)
+	m.metrics.AddCounter("claude_monitor_rejected_requests_total",
+		"Number of @claude requests rejected by the authorization policy", 1)
+	m.postComment(ctx, repo, request.IssueNumber, fmt.Sprintf(
+		"🙏 @%s さん、ご依頼ありがとうございます。\n\n"+
+			"申し訳ありませんが、対象リポジトリ `%s` への書き込み権限を確認できなかったため、このタスクは実行できません。"+
+			"このIssueのリポジトリ以外を対象にする場合は、対象リポジトリへの書き込み権限が必要です。", request.RequestedBy, request.Repository))
+	return false
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// newMembershipServer serves the org and team membership endpoints used by
+// the Authorizer: alice is a member of acme and an active member of
+// acme/core, bob a pending member of acme/core. alice can write to
+// acme/shared and only read acme/private.
+func newMembershipServer(t *testing.T, lookups *int) *github.Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/acme/members/alice", func(w http.ResponseWriter, r *http.Request) {
+		*lookups++
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/orgs/acme/teams/core/memberships/alice", func(w http.ResponseWriter, r *http.Request) {
+		*lookups++
+		w.Write([]byte(`{"state":"active"}`))
+	})
+	mux.HandleFunc("/orgs/acme/teams/core/memberships/bob", func(w http.ResponseWriter, r *http.Request) {
+		*lookups++
+		w.Write([]byte(`{"state":"pending"}`))
+	})
+	mux.HandleFunc("/repos/acme/shared/collaborators/alice/permission", func(w http.ResponseWriter, r *http.Request) {
+		*lookups++
+		w.Write([]byte(`{"permission":"write"}`))
+	})
+	mux.HandleFunc("/repos/acme/private/collaborators/alice/permission", func(w http.ResponseWriter, r *http.Request) {
+		*lookups++
+		w.Write([]byte(`{"permission":"read"}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		*lookups++
+		http.NotFound(w, r)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return client
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      AuthorizationPolicy
+		login       string
+		association string
+		want        bool
+	}{
+		{
+			name:   "unknown user",
+			policy: AuthorizationPolicy{AllowAssociations: defaultAllowedAssociations},
+			want:   false,
+		},
+		{
+			name:        "default policy allows collaborators",
+			policy:      AuthorizationPolicy{AllowAssociations: defaultAllowedAssociations},
+			login:       "carol",
+			association: "COLLABORATOR",
+			want:        true,
+		},
+		{
+			name:        "default policy rejects contributors",
+			policy:      AuthorizationPolicy{AllowAssociations: defaultAllowedAssociations},
+			login:       "carol",
+			association: "CONTRIBUTOR",
+			want:        false,
+		},
+		{
+			name:        "association matches ignoring case",
+			policy:      AuthorizationPolicy{AllowAssociations: []string{"owner"}},
+			login:       "carol",
+			association: "OWNER",
+			want:        true,
+		},
+		{
+			name:   "allowed user ignoring case",
+			policy: AuthorizationPolicy{AllowUsers: []string{"Carol"}},
+			login:  "carol",
+			want:   true,
+		},
+		{
+			name:        "deny wins over allow rules",
+			policy:      AuthorizationPolicy{DenyUsers: []string{"carol"}, AllowUsers: []string{"carol"}, AllowAssociations: []string{"OWNER"}},
+			login:       "carol",
+			association: "OWNER",
+			want:        false,
+		},
+		{
+			name:   "org member",
+			policy: AuthorizationPolicy{AllowOrgs: []string{"acme"}},
+			login:  "alice",
+			want:   true,
+		},
+		{
+			name:   "not an org member",
+			policy: AuthorizationPolicy{AllowOrgs: []string{"acme"}},
+			login:  "mallory",
+			want:   false,
+		},
+		{
+			name:   "active team member",
+			policy: AuthorizationPolicy{AllowTeams: []string{"acme/core"}},
+			login:  "alice",
+			want:   true,
+		},
+		{
+			name:   "pending team member",
+			policy: AuthorizationPolicy{AllowTeams: []string{"acme/core"}},
+			login:  "bob",
+			want:   false,
+		},
+		{
+			name:   "not a team member",
+			policy: AuthorizationPolicy{AllowTeams: []string{"acme/core"}},
+			login:  "mallory",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookups int
+			authorizer := NewAuthorizer(newMembershipServer(t, &lookups), &tt.policy)
+			got, reason := authorizer.Authorize(context.Background(), tt.login, tt.association)
+			if got != tt.want {
+				t.Errorf("Authorize(%q, %q) = %v (%s), want %v", tt.login, tt.association, got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeCachesMembership(t *testing.T) {
+	var lookups int
+	authorizer := NewAuthorizer(newMembershipServer(t, &lookups), &AuthorizationPolicy{
+		AllowOrgs:  []string{"acme"},
+		AllowTeams: []string{"acme/core"},
+	})
+
+	for i := 0; i < 3; i++ {
+		if allowed, reason := authorizer.Authorize(context.Background(), "mallory", ""); allowed {
+			t.Fatalf("Authorize(mallory) = true (%s), want false", reason)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("got %d membership lookups, want 2 (one per rule)", lookups)
+	}
+}
+
+func TestAuthorizeTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		login       string
+		source      string
+		target      string
+		want        bool
+		wantLookups int
+	}{
+		{name: "same repository", login: "mallory", source: "acme/app", target: "acme/app", want: true},
+		{name: "same repository ignoring case", login: "mallory", source: "acme/app", target: "Acme/App", want: true},
+		{name: "write access to the target", login: "alice", source: "acme/app", target: "acme/shared", want: true, wantLookups: 1},
+		{name: "read access to the target", login: "alice", source: "acme/app", target: "acme/private", want: false, wantLookups: 1},
+		{name: "target the requester cannot see", login: "mallory", source: "acme/app", target: "acme/private", want: false, wantLookups: 1},
+		{name: "invalid target", login: "alice", source: "acme/app", target: "private", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lookups int
+			// Even users the policy allows everywhere need access to the target
+			authorizer := NewAuthorizer(newMembershipServer(t, &lookups), &AuthorizationPolicy{AllowUsers: []string{tt.login}})
+			got, reason := authorizer.AuthorizeTarget(context.Background(), tt.login, tt.source, tt.target)
+			if got != tt.want {
+				t.Errorf("AuthorizeTarget(%q, %q, %q) = %v (%s), want %v", tt.login, tt.source, tt.target, got, reason, tt.want)
+			}
+			if lookups != tt.wantLookups {
+				t.Errorf("got %d permission lookups, want %d", lookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestLoadAuthorizationPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string // file contents; no file when empty
+		want    AuthorizationPolicy
+		wantErr bool
+	}{
+		{
+			name: "default without a policy file",
+			want: AuthorizationPolicy{AllowAssociations: defaultAllowedAssociations},
+		},
+		{
+			name:   "allow and deny rules",
+			policy: "deny_users: [mallory]\nallow_teams: [acme/core]\nrejection_message: no\n",
+			want:   AuthorizationPolicy{DenyUsers: []string{"mallory"}, AllowTeams: []string{"acme/core"}, RejectionMessage: "no"},
+		},
+		{name: "team without org", policy: "allow_teams: [core]\n", wantErr: true},
+		{name: "no allow rules", policy: "deny_users: [mallory]\n", wantErr: true},
+		{name: "unknown field", policy: "allow_user: [alice]\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ""
+			if tt.policy != "" {
+				path = filepath.Join(t.TempDir(), "policy.yaml")
+				if err := os.WriteFile(path, []byte(tt.policy), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("MONITOR_POLICY_FILE", path)
+
+			got, err := loadAuthorizationPolicy()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("loadAuthorizationPolicy() = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadAuthorizationPolicy() failed: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("loadAuthorizationPolicy() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
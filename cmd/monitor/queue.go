@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// queuedTask is a task waiting in or running from the work queue
+type queuedTask struct {
+	request     IssueRequest
+	repo        watchedRepository // repository where the mention occurred
+	ctx         context.Context
+	cancel      context.CancelFunc
+	enqueuedAt  time.Time
+	startedAt   time.Time
+	podName     string
+	cancelledBy string
+}
+
+// key identifies the issue a task belongs to
+func (t *queuedTask) key() string {
+	return issueKey(t.repo.FullName(), t.request.IssueNumber)
+}
+
+// workQueue runs tasks with a global concurrency cap, at most maxPerRepo
+// tasks per target repository and at most one task per issue at a time.
+// Tasks start in the order they were queued as soon as their limits allow.
+type workQueue struct {
+	maxWorkers int
+	maxPerRepo int
+	run        func(*queuedTask)
+	metrics    *Metrics
+
+	// Task contexts derive from baseCtx rather than the monitor's context so
+	// running tasks can finish while the monitor drains on shutdown
+	baseCtx    context.Context
+	baseCancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending []*queuedTask
+	running map[string]*queuedTask // owner/repo#issue -> task
+	perRepo map[string]int         // target repository -> running tasks
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func newWorkQueue(maxWorkers, maxPerRepo int, metrics *Metrics, run func(*queuedTask)) *workQueue {
+	baseCtx, baseCancel := context.WithCancel(context.Background())
+	return &workQueue{
+		maxWorkers: maxWorkers,
+		maxPerRepo: maxPerRepo,
+		run:        run,
+		metrics:    metrics,
+		baseCtx:    baseCtx,
+		baseCancel: baseCancel,
+		running:    make(map[string]*queuedTask),
+		perRepo:    make(map[string]int),
+	}
+}
+
+// Enqueue adds a request to the queue and returns its position among the
+// waiting tasks, or 0 when it started immediately
+func (q *workQueue) Enqueue(repo watchedRepository, request *IssueRequest) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return 0, fmt.Errorf("monitor is shutting down")
+	}
+
+	ctx, cancel := context.WithCancel(q.baseCtx)
+	task := &queuedTask{
+		request:    *request,
+		repo:       repo,
+		ctx:        ctx,
+		cancel:     cancel,
+		enqueuedAt: time.Now(),
+	}
+	q.pending = append(q.pending, task)
+	q.scheduleLocked()
+
+	for i, pending := range q.pending {
+		if pending == task {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// scheduleLocked starts every pending task whose limits allow it
+func (q *workQueue) scheduleLocked() {
+	remaining := q.pending[:0]
+	for _, task := range q.pending {
+		if q.closed || len(q.running) >= q.maxWorkers ||
+			q.running[task.key()] != nil || q.perRepo[task.request.Repository] >= q.maxPerRepo {
+			remaining = append(remaining, task)
+			continue
+		}
+
+		task.startedAt = time.Now()
+		q.running[task.key()] = task
+		q.perRepo[task.request.Repository]++
+		q.wg.Add(1)
+		go q.execute(task)
+	}
+	q.pending = remaining
+	q.updateMetricsLocked()
+}
+
+func (q *workQueue) execute(task *queuedTask) {
+	defer q.wg.Done()
+	defer func() {
+		task.cancel()
+
+		q.mu.Lock()
+		delete(q.running, task.key())
+		if q.perRepo[task.request.Repository]--; q.perRepo[task.request.Repository] <= 0 {
+			delete(q.perRepo, task.request.Repository)
+		}
+		q.scheduleLocked()
+		q.mu.Unlock()
+	}()
+
+	if waited := task.startedAt.Sub(task.enqueuedAt); waited > time.Second {
+		log.Printf("Starting queued task for %s after %v", task.key(), waited.Round(time.Second))
+	}
+	q.run(task)
+}
+
+// Running returns a copy of the task running for an issue
+func (q *workQueue) Running(key string) (queuedTask, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	task, ok := q.running[key]
+	if !ok {
+		return queuedTask{}, false
+	}
+	return *task, true
+}
+
+// Positions returns the queue positions of an issue's waiting tasks
+func (q *workQueue) Positions(key string) []int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var positions []int
+	for i, task := range q.pending {
+		if task.key() == key {
+			positions = append(positions, i+1)
+		}
+	}
+	return positions
+}
+
+// SetPodName records the worker pod of an issue's running task
+func (q *workQueue) SetPodName(key, podName string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if task, ok := q.running[key]; ok {
+		task.podName = podName
+	}
+}
+
+// Cancel drops an issue's waiting tasks and cancels its running task. It
+// reports whether a task was running and how many waiting tasks were dropped.
+func (q *workQueue) Cancel(key, cancelledBy string) (bool, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	dropped := 0
+	remaining := q.pending[:0]
+	for _, task := range q.pending {
+		if task.key() == key {
+			task.cancel()
+			dropped++
+			continue
+		}
+		remaining = append(remaining, task)
+	}
+	q.pending = remaining
+	q.updateMetricsLocked()
+
+	task, running := q.running[key]
+	if running {
+		task.cancelledBy = cancelledBy
+		task.cancel()
+	}
+	return running, dropped
+}
+
+// CancelledBy returns who cancelled an issue's running task
+func (q *workQueue) CancelledBy(key string) string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if task, ok := q.running[key]; ok {
+		return task.cancelledBy
+	}
+	return ""
+}
+
+// Drain stops accepting tasks, waits up to timeout for running tasks and
+// then cancels the rest. It returns the tasks that never started.
+func (q *workQueue) Drain(timeout time.Duration) []*queuedTask {
+	q.mu.Lock()
+	q.closed = true
+	dropped := q.pending
+	q.pending = nil
+	running := len(q.running)
+	q.updateMetricsLocked()
+	q.mu.Unlock()
+
+	for _, task := range dropped {
+		task.cancel()
+	}
+
+	log.Printf("Draining work queue: waiting up to %v for %d running tasks (%d queued tasks dropped)",
+		timeout, running, len(dropped))
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("Work queue drained")
+	case <-time.After(timeout):
+		log.Printf("Drain timeout reached, cancelling running tasks")
+		q.baseCancel()
+		<-done
+	}
+	return dropped
+}
+
+func (q *workQueue) updateMetricsLocked() {
+	q.metrics.SetGauge("claude_monitor_queue_pending",
+		"Number of tasks waiting for a worker", float64(len(q.pending)))
+	q.metrics.SetGauge("claude_monitor_workers_running",
+		"Number of tasks currently running", float64(len(q.running)))
+}
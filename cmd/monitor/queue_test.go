@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// queueRequest is an @claude request on issue of repository source that
+// works on repository target
+type queueRequest struct {
+	source string
+	issue  int
+	target string
+}
+
+// newBlockingQueue returns a queue whose tasks run until they are cancelled
+// and a channel receiving the key of each started task
+func newBlockingQueue(t *testing.T, maxWorkers, maxPerRepo int) (*workQueue, chan string) {
+	started := make(chan string, 16)
+	q := newWorkQueue(maxWorkers, maxPerRepo, NewMetrics(), func(task *queuedTask) {
+		started <- task.key()
+		<-task.ctx.Done()
+	})
+	t.Cleanup(func() { q.Drain(10 * time.Millisecond) })
+	return q, started
+}
+
+func enqueue(t *testing.T, q *workQueue, r queueRequest) int {
+	t.Helper()
+	repo, err := parseRepository(r.source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	position, err := q.Enqueue(repo, &IssueRequest{IssueNumber: r.issue, Repository: r.target, SourceRepository: r.source})
+	if err != nil {
+		t.Fatalf("Enqueue(%+v) failed: %v", r, err)
+	}
+	return position
+}
+
+func TestWorkQueueLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxWorkers    int
+		maxPerRepo    int
+		requests      []queueRequest
+		wantPositions []int // 0 when the task started immediately
+	}{
+		{
+			name:       "global cap",
+			maxWorkers: 2,
+			maxPerRepo: 2,
+			requests: []queueRequest{
+				{"org/a", 1, "org/a"},
+				{"org/b", 1, "org/b"},
+				{"org/c", 1, "org/c"},
+				{"org/d", 1, "org/d"},
+			},
+			wantPositions: []int{0, 0, 1, 2},
+		},
+		{
+			name:       "per-repository cap counts the target repository",
+			maxWorkers: 4,
+			maxPerRepo: 1,
+			requests: []queueRequest{
+				{"org/a", 1, "org/a"},
+				{"org/b", 1, "org/a"},
+				{"org/b", 2, "org/b"},
+			},
+			wantPositions: []int{0, 1, 0},
+		},
+		{
+			name:       "one task per issue",
+			maxWorkers: 4,
+			maxPerRepo: 4,
+			requests: []queueRequest{
+				{"org/a", 1, "org/a"},
+				{"org/a", 1, "org/a"},
+				{"org/a", 2, "org/a"},
+			},
+			wantPositions: []int{0, 1, 0},
+		},
+		{
+			name:       "waiting tasks do not block others",
+			maxWorkers: 2,
+			maxPerRepo: 1,
+			requests: []queueRequest{
+				{"org/a", 1, "org/a"},
+				{"org/a", 2, "org/a"},
+				{"org/a", 3, "org/a"},
+				{"org/b", 1, "org/b"},
+				{"org/c", 1, "org/c"},
+			},
+			wantPositions: []int{0, 1, 2, 0, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := newBlockingQueue(t, tt.maxWorkers, tt.maxPerRepo)
+			for i, r := range tt.requests {
+				if got := enqueue(t, q, r); got != tt.wantPositions[i] {
+					t.Errorf("request %d (%+v): position = %d, want %d", i, r, got, tt.wantPositions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWorkQueueStartsWaitingTaskWhenSlotFrees(t *testing.T) {
+	q, started := newBlockingQueue(t, 1, 1)
+
+	enqueue(t, q, queueRequest{"org/a", 1, "org/a"})
+	if position := enqueue(t, q, queueRequest{"org/a", 2, "org/a"}); position != 1 {
+		t.Fatalf("second task position = %d, want 1", position)
+	}
+	if key := <-started; key != "org/a#1" {
+		t.Fatalf("first started task = %s, want org/a#1", key)
+	}
+
+	if running, dropped := q.Cancel("org/a#1", "tester"); !running || dropped != 0 {
+		t.Fatalf("Cancel(org/a#1) = %v, %d, want true, 0", running, dropped)
+	}
+	select {
+	case key := <-started:
+		if key != "org/a#2" {
+			t.Fatalf("next started task = %s, want org/a#2", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting task did not start after the running one finished")
+	}
+	if positions := q.Positions("org/a#2"); len(positions) != 0 {
+		t.Errorf("Positions(org/a#2) = %v after it started, want none", positions)
+	}
+}
+
+func TestWorkQueueRejectsTasksWhileDraining(t *testing.T) {
+	q, _ := newBlockingQueue(t, 1, 1)
+	enqueue(t, q, queueRequest{"org/a", 1, "org/a"})
+	enqueue(t, q, queueRequest{"org/a", 2, "org/a"})
+
+	if dropped := q.Drain(10 * time.Millisecond); len(dropped) != 1 || dropped[0].key() != "org/a#2" {
+		t.Errorf("Drain dropped %d tasks, want the waiting org/a#2", len(dropped))
+	}
+	repo, _ := parseRepository("org/a")
+	if _, err := q.Enqueue(repo, &IssueRequest{IssueNumber: 3, Repository: "org/a"}); err == nil {
+		t.Error("Enqueue after Drain succeeded, want error")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// maxRateLimitRetries bounds how often a single call waits for the rate limit
+	maxRateLimitRetries = 3
+	// maxRateLimitWait caps a single back-off so shutdown stays responsive
+	maxRateLimitWait = 15 * time.Minute
+	// defaultSecondaryWait is used when a secondary rate limit has no Retry-After
+	defaultSecondaryWait = time.Minute
+)
+
+// callGitHub runs a GitHub API call, recording the rate limit from the
+// response and backing off on primary (X-RateLimit-Reset) and secondary
+// (Retry-After) rate limit errors
+func (m *IssueMonitor) callGitHub(ctx context.Context, call func() (*github.Response, error)) error {
+	for attempt := 0; ; attempt++ {
+		resp, err := call()
+		m.observeRate(resp)
+		if err == nil {
+			return nil
+		}
+
+		wait, limited := rateLimitWait(err)
+		if !limited {
+			return err
+		}
+		if attempt >= maxRateLimitRetries {
+			return fmt.Errorf("rate limited after %d retries: %w", attempt, err)
+		}
+		if wait > maxRateLimitWait {
+			wait = maxRateLimitWait
+		}
+
+		log.Printf("GitHub rate limit hit, backing off for %v: %v", wait.Round(time.Second), err)
+		m.metrics.AddCounter("claude_monitor_github_rate_limit_waits_total",
+			"Number of times the monitor backed off because of GitHub rate limits", 1)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// rateLimitWait returns how long to wait before retrying after err
+func rateLimitWait(err error) (time.Duration, bool) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		// Add a second of slack for clock skew
+		return time.Until(rateErr.Rate.Reset.Time) + time.Second, true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		if retryAfter := abuseErr.GetRetryAfter(); retryAfter > 0 {
+			return retryAfter, true
+		}
+		return defaultSecondaryWait, true
+	}
+
+	return 0, false
+}
+
+// observeRate exports the rate limit reported with a GitHub response
+func (m *IssueMonitor) observeRate(resp *github.Response) {
+	m.metrics.AddCounter("claude_monitor_github_requests_total",
+		"Number of GitHub API requests made by the monitor", 1)
+	if resp == nil || resp.Rate.Limit == 0 {
+		return
+	}
+
+	m.metrics.SetGauge("claude_monitor_github_rate_limit",
+		"GitHub API rate limit for the monitor token", float64(resp.Rate.Limit))
+	m.metrics.SetGauge("claude_monitor_github_rate_limit_remaining",
+		"Remaining GitHub API requests in the current window", float64(resp.Rate.Remaining))
+	m.metrics.SetGauge("claude_monitor_github_rate_limit_reset_timestamp_seconds",
+		"Unix time at which the GitHub API rate limit resets", float64(resp.Rate.Reset.Unix()))
+
+	m.rateMu.Lock()
+	m.lastRate = resp.Rate
+	m.rateMu.Unlock()
+}
+
+// logRate logs the most recently observed rate limit
+func (m *IssueMonitor) logRate() {
+	m.rateMu.Lock()
+	rate := m.lastRate
+	m.rateMu.Unlock()
+
+	if rate.Limit == 0 {
+		return
+	}
+	log.Printf("GitHub API quota: %d/%d remaining (resets at %s)",
+		rate.Remaining, rate.Limit, rate.Reset.Format(time.RFC3339))
+}
+
+// listUpdatedIssues walks every page of issues updated since the given time
+func (m *IssueMonitor) listUpdatedIssues(ctx context.Context, repo watchedRepository, since time.Time) ([]*github.Issue, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:     "open",
+		Sort:      "updated",
+		Direction: "desc",
+		Since:     since,
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var all []*github.Issue
+	for {
+		var issues []*github.Issue
+		var resp *github.Response
+		err := m.callGitHub(ctx, func() (*github.Response, error) {
+			var err error
+			issues, resp, err = m.client.Issues.ListByRepo(ctx, repo.Owner, repo.Name, opts)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, issues...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// listIssueComments walks every page of comments updated since the given time
+func (m *IssueMonitor) listIssueComments(ctx context.Context, repo watchedRepository, issueNumber int, since time.Time) ([]*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{
+		Since: &since,
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	var all []*github.IssueComment
+	for {
+		var comments []*github.IssueComment
+		var resp *github.Response
+		err := m.callGitHub(ctx, func() (*github.Response, error) {
+			var err error
+			comments, resp, err = m.client.Issues.ListComments(ctx, repo.Owner, repo.Name, issueNumber, opts)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, comments...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// labelActor returns who last added a label to an issue, or "" when the
+// issue events do not show it
+func (m *IssueMonitor) labelActor(ctx context.Context, repo watchedRepository, issueNumber int, label string) (string, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	actor := ""
+	for {
+		var events []*github.IssueEvent
+		var resp *github.Response
+		err := m.callGitHub(ctx, func() (*github.Response, error) {
+			var err error
+			events, resp, err = m.client.Issues.ListIssueEvents(ctx, repo.Owner, repo.Name, issueNumber, opts)
+			return resp, err
+		})
+		if err != nil {
+			return "", err
+		}
+
+		// Events are listed oldest first
+		for _, event := range events {
+			if event.GetEvent() == "labeled" && strings.EqualFold(event.GetLabel().GetName(), label) {
+				actor = event.GetActor().GetLogin()
+			}
+		}
+		if resp.NextPage == 0 {
+			return actor, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
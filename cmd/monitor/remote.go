@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/claude-automation/pkg/orchestrator"
+)
+
+// remotePollInterval is how often the status of an orchestrator task is checked
+const remotePollInterval = 5 * time.Second
+
+// runRemoteTask submits a task to the orchestrator service and waits for it
+// to finish. The orchestrator posts the progress and result comments itself.
+func (m *IssueMonitor) runRemoteTask(ctx context.Context, repo watchedRepository, request *IssueRequest) {
+	issueNumber := request.IssueNumber
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	task, err := m.orchestrator.Submit(ctx, orchestrator.TaskRequest{
+		IssueNumber:      issueNumber,
+		Task:             request.Task,
+		Repository:       request.Repository,
+		SourceRepository: request.SourceRepository,
+		Ref:              request.Ref,
+		MaxTurns:         request.MaxTurns,
+		FollowUp:         request.FollowUp,
+		RequestedBy:      request.RequestedBy,
+		Labels:           request.Labels,
+	})
+	if err != nil {
+		log.Printf("Failed to submit %s#%d to orchestrator: %v", repo.FullName(), issueNumber, err)
+		if m.reportCancelled(ctx, repo, request, "") {
+			return
+		}
+		m.postComment(cleanupCtx, repo, issueNumber, fmt.Sprintf("❌ **オーケストレーターへのタスク送信に失敗しました**\n\n```\n%v\n```\n\n`@claude retry` で再実行できます。", err))
+		return
+	}
+	log.Printf("Submitted %s#%d to orchestrator as %s", repo.FullName(), issueNumber, task.ID)
+
+	ticker := time.NewTicker(remotePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.cancelRemoteTask(cleanupCtx, repo, request, task.ID)
+			return
+		case <-ticker.C:
+		}
+
+		task, err = m.orchestrator.Get(ctx, task.ID)
+		if err != nil {
+			var apiErr *orchestrator.APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				log.Printf("Orchestrator no longer knows task for %s#%d", repo.FullName(), issueNumber)
+				m.postComment(cleanupCtx, repo, issueNumber, "⚠️ **オーケストレーターでタスクが見つからなくなりました**\n\nオーケストレーターが再起動した可能性があります。`@claude retry` で再実行してください。")
+				return
+			}
+			if ctx.Err() == nil {
+				log.Printf("Warning: failed to get status of orchestrator task for %s#%d: %v", repo.FullName(), issueNumber, err)
+			}
+			continue
+		}
+
+		if task.Worker != "" {
+			m.setTaskPod(repo, issueNumber, task.Worker)
+		}
+		if task.State.Done() {
+			m.logRemoteResult(cleanupCtx, repo, task)
+			return
+		}
+	}
+}
+
+// cancelRemoteTask cancels the orchestrator task when a user cancelled it.
+// When the monitor is shutting down the orchestrator keeps running the task.
+func (m *IssueMonitor) cancelRemoteTask(ctx context.Context, repo watchedRepository, request *IssueRequest, id string) {
+	by := m.cancelledBy(repo, request.IssueNumber)
+	if by == "" {
+		log.Printf("Monitor stopped waiting for orchestrator task %s (%s#%d); it keeps running", id, repo.FullName(), request.IssueNumber)
+		return
+	}
+
+	// The orchestrator posts the cancellation comment
+	if _, err := m.orchestrator.Cancel(ctx, id, by); err != nil {
+		log.Printf("Failed to cancel orchestrator task %s: %v", id, err)
+		m.postComment(ctx, repo, request.IssueNumber, fmt.Sprintf("⚠️ **タスクのキャンセルに失敗しました**\n\n```\n%v\n```", err))
+	}
+}
+
+func (m *IssueMonitor) logRemoteResult(ctx context.Context, repo watchedRepository, task *orchestrator.Task) {
+	result, err := m.orchestrator.Result(ctx, task.ID)
+	if err != nil {
+		log.Printf("Orchestrator task %s for %s#%d finished (%s)", task.ID, repo.FullName(), task.Request.IssueNumber, task.State)
+		return
+	}
+	log.Printf("Orchestrator task %s for %s#%d finished (%s, subtype: %s, turns: %d, cost: $%.4f, pull request: %s)",
+		task.ID, repo.FullName(), task.Request.IssueNumber, result.State, result.Subtype, result.NumTurns, result.CostUSD, result.PullRequestURL)
+}
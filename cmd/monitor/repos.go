@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/claude-automation/pkg/config"
+	"github.com/google/go-github/v57/github"
+)
+
+// repoRefreshInterval controls how often org patterns are re-expanded so new
+// repositories are picked up without a restart
+const repoRefreshInterval = 10 * time.Minute
+
+// watchedRepository is a repository the monitor scans for @claude mentions
+type watchedRepository struct {
+	Owner string
+	Name  string
+}
+
+// FullName returns the repository as owner/name
+func (r watchedRepository) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+// parseRepository parses an owner/name string
+func parseRepository(fullName string) (watchedRepository, error) {
+	parts := strings.Split(strings.TrimSpace(fullName), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return watchedRepository{}, fmt.Errorf("invalid repository %q (expected owner/name)", fullName)
+	}
+	return watchedRepository{Owner: parts[0], Name: parts[1]}, nil
+}
+
+// repositorySet holds the configured repositories and org patterns, such as
+// "worldscandy/claude-automation", "worldscandy/*-service" or "worldscandy/*"
+type repositorySet struct {
+	patterns []string
+
+	mu         sync.Mutex
+	resolved   []watchedRepository
+	resolvedAt time.Time
+}
+
+// newRepositorySet builds the watch list from GITHUB_REPOS, falling back to
+// GITHUB_OWNER/GITHUB_REPO, plus the repositories named in MONITOR_REPO_MAPPING
+func newRepositorySet(defaultRepository string) (*repositorySet, error) {
+	var patterns []string
+	for _, entry := range strings.Split(os.Getenv("GITHUB_REPOS"), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			patterns = append(patterns, entry)
+		}
+	}
+	if len(patterns) == 0 {
+		patterns = append(patterns, defaultRepository)
+	}
+
+	if mappingPath := os.Getenv("MONITOR_REPO_MAPPING"); mappingPath != "" {
+		names, err := loadMappedRepositories(mappingPath)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, names...)
+	}
+
+	for _, pattern := range patterns {
+		repo, err := parseRepository(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := path.Match(strings.ToLower(repo.FullName()), ""); err != nil {
+			return nil, fmt.Errorf("invalid repository pattern %q: %w", pattern, err)
+		}
+	}
+
+	return &repositorySet{patterns: patterns}, nil
+}
+
+// loadMappedRepositories returns the repository keys of a repo-mapping.yaml file
+func loadMappedRepositories(mappingPath string) ([]string, error) {
+	mapping, err := config.LoadRepoMapping(mappingPath)
+	if err != nil {
+		return nil, err
+	}
+	return mapping.RepositoryNames(), nil
+}
+
+// Matches reports whether a repository is covered by the watch list
+func (rs *repositorySet) Matches(fullName string) bool {
+	fullName = strings.ToLower(fullName)
+	for _, pattern := range rs.patterns {
+		if matched, _ := path.Match(strings.ToLower(pattern), fullName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (rs *repositorySet) String() string {
+	return strings.Join(rs.patterns, ", ")
+}
+
+// isPattern reports whether a watch list entry contains glob characters
+func isPattern(entry string) bool {
+	return strings.ContainsAny(entry, "*?[")
+}
+
+// watchedRepositories expands the watch list into concrete repositories,
+// listing the owning organization (or user) for entries with patterns
+func (m *IssueMonitor) watchedRepositories(ctx context.Context) ([]watchedRepository, error) {
+	rs := m.repositories
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.resolved != nil && time.Since(rs.resolvedAt) < repoRefreshInterval {
+		return rs.resolved, nil
+	}
+
+	seen := make(map[string]bool)
+	var resolved []watchedRepository
+	add := func(repo watchedRepository) {
+		key := strings.ToLower(repo.FullName())
+		if !seen[key] {
+			seen[key] = true
+			resolved = append(resolved, repo)
+		}
+	}
+
+	ownerRepos := make(map[string][]*github.Repository)
+	for _, pattern := range rs.patterns {
+		target, _ := parseRepository(pattern)
+		if !isPattern(pattern) {
+			add(target)
+			continue
+		}
+
+		repos, listed := ownerRepos[target.Owner]
+		if !listed {
+			var err error
+			repos, err = m.listOwnerRepositories(ctx, target.Owner)
+			if err != nil {
+				// Keep the previous expansion rather than dropping repositories
+				if rs.resolved != nil {
+					log.Printf("Warning: failed to refresh repositories for %s: %v", target.Owner, err)
+					return rs.resolved, nil
+				}
+				return nil, err
+			}
+			ownerRepos[target.Owner] = repos
+		}
+
+		for _, repo := range repos {
+			if repo.GetArchived() {
+				continue
+			}
+			candidate := watchedRepository{Owner: target.Owner, Name: repo.GetName()}
+			if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(candidate.FullName())); matched {
+				add(candidate)
+			}
+		}
+	}
+
+	rs.resolved = resolved
+	rs.resolvedAt = time.Now()
+	m.metrics.SetGauge("claude_monitor_watched_repositories",
+		"Number of repositories watched by the monitor", float64(len(resolved)))
+
+	names := make([]string, 0, len(resolved))
+	for _, repo := range resolved {
+		names = append(names, repo.FullName())
+	}
+	log.Printf("Watching %d repositories: %s", len(resolved), strings.Join(names, ", "))
+
+	return resolved, nil
+}
+
+// listOwnerRepositories lists all repositories of an organization, falling
+// back to the user listing for personal accounts
+func (m *IssueMonitor) listOwnerRepositories(ctx context.Context, owner string) ([]*github.Repository, error) {
+	var all []*github.Repository
+
+	orgOpts := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		var repos []*github.Repository
+		var resp *github.Response
+		err := m.callGitHub(ctx, func() (*github.Response, error) {
+			var err error
+			repos, resp, err = m.client.Repositories.ListByOrg(ctx, owner, orgOpts)
+			return resp, err
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return m.listUserRepositories(ctx, owner)
+			}
+			return nil, fmt.Errorf("failed to list repositories for %s: %w", owner, err)
+		}
+
+		all = append(all, repos...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		orgOpts.Page = resp.NextPage
+	}
+}
+
+func (m *IssueMonitor) listUserRepositories(ctx context.Context, user string) ([]*github.Repository, error) {
+	var all []*github.Repository
+
+	userOpts := &github.RepositoryListByUserOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		var repos []*github.Repository
+		var resp *github.Response
+		err := m.callGitHub(ctx, func() (*github.Response, error) {
+			var err error
+			repos, resp, err = m.client.Repositories.ListByUser(ctx, user, userOpts)
+			return resp, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repositories for %s: %w", user, err)
+		}
+
+		all = append(all, repos...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		userOpts.Page = resp.NextPage
+	}
+}
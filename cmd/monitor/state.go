@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/claude-automation/pkg/kubernetes"
+)
+
+// triggerRetention bounds how long processed triggers, and the last comment
+// and request of issues without activity, are remembered
+const triggerRetention = 90 * 24 * time.Hour
+
+// stateConfigMapKey is the ConfigMap key holding the encoded monitor state
+const stateConfigMapKey = "state.json"
+
+// ProcessedTrigger records a @claude mention that has already been handled
+type ProcessedTrigger struct {
+	Repository  string    `json:"repository"`
+	IssueNumber int       `json:"issue_number"`
+	CommentID   int64     `json:"comment_id,omitempty"` // 0 for the issue body
+	BodyHash    string    `json:"body_hash"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// monitorState is the persisted monitor state
+type monitorState struct {
+	Triggers       map[string]ProcessedTrigger `json:"triggers"`
+	Cursors        map[string]time.Time        `json:"cursors,omitempty"`          // owner/repo -> last checked
+	LastChecked    time.Time                   `json:"last_checked,omitempty"`     // single-repository cursor from older monitors
+	LastCommentIDs map[string]int64            `json:"last_comment_ids,omitempty"` // owner/repo#issue -> comment ID
+	LastRequests   map[string]IssueRequest     `json:"last_requests,omitempty"`    // owner/repo#issue -> last task
+	IssueActivity  map[string]time.Time        `json:"issue_activity,omitempty"`   // owner/repo#issue -> last comment or request recorded
+}
+
+// stateBackend loads and saves the encoded monitor state
+type stateBackend interface {
+	// Load returns nil data when no state has been saved yet
+	Load() ([]byte, error)
+	Save(data []byte) error
+	String() string
+}
+
+// fileStateBackend stores state in a local JSON file
+type fileStateBackend struct {
+	path string
+}
+
+func (b *fileStateBackend) Load() ([]byte, error) {
+	data, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Save writes the state atomically via a temporary file
+func (b *fileStateBackend) Save(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return os.Rename(tmp, b.path)
+}
+
+func (b *fileStateBackend) String() string {
+	return "file " + b.path
+}
+
+// configMapStateBackend stores state in a ConfigMap so it survives pod rescheduling
+type configMapStateBackend struct {
+	podManager *kubernetes.PodManager
+	name       string
+}
+
+func (b *configMapStateBackend) Load() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	value, found, err := b.podManager.GetConfigMapValue(ctx, b.name, stateConfigMapKey)
+	if err != nil || !found {
+		return nil, err
+	}
+	return []byte(value), nil
+}
+
+func (b *configMapStateBackend) Save(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return b.podManager.SetConfigMapValue(ctx, b.name, stateConfigMapKey, string(data))
+}
+
+func (b *configMapStateBackend) String() string {
+	return "configmap " + b.name
+}
+
+// StateStore persists monitor state so that mentions are processed exactly
+// once and the polling cursor survives monitor restarts
+type StateStore struct {
+	backend stateBackend
+	mu      sync.Mutex
+	state   monitorState
+}
+
+// NewStateStore loads the state from the backend, starting empty if nothing
+// has been saved yet
+func NewStateStore(backend stateBackend) (*StateStore, error) {
+	store := &StateStore{
+		backend: backend,
+		state: monitorState{
+			Triggers:       make(map[string]ProcessedTrigger),
+			Cursors:        make(map[string]time.Time),
+			LastCommentIDs: make(map[string]int64),
+			LastRequests:   make(map[string]IssueRequest),
+			IssueActivity:  make(map[string]time.Time),
+		},
+	}
+
+	data, err := backend.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state from %s: %w", backend, err)
+	}
+	if data == nil {
+		log.Printf("No monitor state found in %s, starting fresh", backend)
+		return store, nil
+	}
+
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, fmt.Errorf("failed to parse state from %s: %w", backend, err)
+	}
+	if store.state.Triggers == nil {
+		store.state.Triggers = make(map[string]ProcessedTrigger)
+	}
+	if store.state.Cursors == nil {
+		store.state.Cursors = make(map[string]time.Time)
+	}
+	if store.state.LastCommentIDs == nil {
+		store.state.LastCommentIDs = make(map[string]int64)
+	}
+	if store.state.LastRequests == nil {
+		store.state.LastRequests = make(map[string]IssueRequest)
+	}
+	if store.state.IssueActivity == nil {
+		store.state.IssueActivity = make(map[string]time.Time)
+	}
+	// State saved before issue activity was recorded is kept for a full
+	// retention period from now
+	for key := range store.state.LastCommentIDs {
+		store.touchLocked(key)
+	}
+	for key := range store.state.LastRequests {
+		store.touchLocked(key)
+	}
+
+	log.Printf("Loaded monitor state from %s (%d processed triggers, %d repository cursors)",
+		backend, len(store.state.Triggers), len(store.state.Cursors))
+	return store, nil
+}
+
+// ClaimTrigger records a trigger and reports whether it should be processed.
+// It returns false when the same (issue, comment, body) was handled before,
+// unless force is set. The trigger is persisted before it is processed so a
+// crash mid-task does not cause a duplicate run on restart.
+func (s *StateStore) ClaimTrigger(repository string, issueNumber int, commentID int64, body string, force bool) bool {
+	hash := hashBody(body)
+	key := triggerKey(repository, issueNumber, commentID, hash)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, seen := s.state.Triggers[key]; seen && !force {
+		return false
+	}
+
+	s.state.Triggers[key] = ProcessedTrigger{
+		Repository:  repository,
+		IssueNumber: issueNumber,
+		CommentID:   commentID,
+		BodyHash:    hash,
+		ProcessedAt: time.Now(),
+	}
+	s.pruneLocked()
+
+	if err := s.saveLocked(); err != nil {
+		log.Printf("Warning: failed to persist monitor state: %v", err)
+	}
+	return true
+}
+
+// ResumeCursor returns the time polling of a repository should resume from.
+// A saved cursor older than catchUpWindow is clamped to the window; without a
+// saved cursor polling starts now.
+func (s *StateStore) ResumeCursor(repository string, catchUpWindow time.Duration) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cursor, ok := s.state.Cursors[repository]
+	if !ok {
+		cursor = s.state.LastChecked
+	}
+	if cursor.IsZero() {
+		return now
+	}
+
+	earliest := now.Add(-catchUpWindow)
+	if cursor.Before(earliest) {
+		log.Printf("Saved cursor for %s (%s) is older than the catch-up window (%v), resuming from %s",
+			repository, cursor.Format(time.RFC3339), catchUpWindow, earliest.Format(time.RFC3339))
+		return earliest
+	}
+	return cursor
+}
+
+// AdvanceCursor persists a repository's polling cursor after a successful
+// scan. A cursor that did not move is not saved again.
+func (s *StateStore) AdvanceCursor(repository string, lastChecked time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !lastChecked.After(s.state.Cursors[repository]) {
+		return
+	}
+	s.state.Cursors[repository] = lastChecked
+	if err := s.saveLocked(); err != nil {
+		log.Printf("Warning: failed to persist monitor cursor: %v", err)
+	}
+}
+
+// LastCommentID returns the newest comment ID seen on an issue
+func (s *StateStore) LastCommentID(repository string, issueNumber int) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state.LastCommentIDs[issueKey(repository, issueNumber)]
+}
+
+// RecordCommentID remembers the newest comment ID seen on an issue. It is
+// saved together with the next cursor update.
+func (s *StateStore) RecordCommentID(repository string, issueNumber int, commentID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := issueKey(repository, issueNumber)
+	if commentID > s.state.LastCommentIDs[key] {
+		s.state.LastCommentIDs[key] = commentID
+		s.state.IssueActivity[key] = time.Now()
+	}
+}
+
+// RecordRequest remembers the last task requested on an issue so that
+// "retry" and "continue" work across monitor restarts
+func (s *StateStore) RecordRequest(request *IssueRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := issueKey(request.SourceRepository, request.IssueNumber)
+	s.state.LastRequests[key] = *request
+	s.state.IssueActivity[key] = time.Now()
+	s.pruneLocked()
+	if err := s.saveLocked(); err != nil {
+		log.Printf("Warning: failed to persist last request: %v", err)
+	}
+}
+
+// LastRequest returns the last task requested on an issue
+func (s *StateStore) LastRequest(repository string, issueNumber int) (*IssueRequest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, ok := s.state.LastRequests[issueKey(repository, issueNumber)]
+	if !ok {
+		return nil, false
+	}
+	return &request, true
+}
+
+// pruneLocked drops triggers, and the last comment and request of issues,
+// older than triggerRetention so the state stays small enough for a ConfigMap
+func (s *StateStore) pruneLocked() {
+	cutoff := time.Now().Add(-triggerRetention)
+	for key, trigger := range s.state.Triggers {
+		if trigger.ProcessedAt.Before(cutoff) {
+			delete(s.state.Triggers, key)
+		}
+	}
+	for key, active := range s.state.IssueActivity {
+		if active.Before(cutoff) {
+			delete(s.state.LastCommentIDs, key)
+			delete(s.state.LastRequests, key)
+			delete(s.state.IssueActivity, key)
+		}
+	}
+}
+
+// touchLocked records activity on an issue that has none recorded yet
+func (s *StateStore) touchLocked(key string) {
+	if _, ok := s.state.IssueActivity[key]; !ok {
+		s.state.IssueActivity[key] = time.Now()
+	}
+}
+
+func (s *StateStore) saveLocked() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	return s.backend.Save(data)
+}
+
+// newStateBackend selects the state backend from MONITOR_STATE_BACKEND
+func newStateBackend(podManager *kubernetes.PodManager) (stateBackend, error) {
+	switch backend := os.Getenv("MONITOR_STATE_BACKEND"); backend {
+	case "", "file":
+		path := os.Getenv("MONITOR_STATE_FILE")
+		if path == "" {
+			path = "/app/sessions/monitor-state.json"
+		}
+		return &fileStateBackend{path: path}, nil
+	case "configmap":
+		name := os.Getenv("MONITOR_STATE_CONFIGMAP")
+		if name == "" {
+			name = "claude-monitor-state"
+		}
+		return &configMapStateBackend{podManager: podManager, name: name}, nil
+	default:
+		return nil, fmt.Errorf("invalid MONITOR_STATE_BACKEND %q (expected \"file\" or \"configmap\")", backend)
+	}
+}
+
+func triggerKey(repository string, issueNumber int, commentID int64, bodyHash string) string {
+	return fmt.Sprintf("%s#%d/%d/%s", repository, issueNumber, commentID, bodyHash)
+}
+
+func issueKey(repository string, issueNumber int) string {
+	return repository + "#" + strconv.Itoa(issueNumber)
+}
+
+func hashBody(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type triggerClaim struct {
+	repository  string
+	issueNumber int
+	commentID   int64
+	body        string
+	force       bool
+	want        bool
+}
+
+func TestClaimTrigger(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims []triggerClaim
+	}{
+		{
+			name: "first claim is processed, a repeat is not",
+			claims: []triggerClaim{
+				{repository: "org/app", issueNumber: 1, commentID: 10, body: "@claude fix", want: true},
+				{repository: "org/app", issueNumber: 1, commentID: 10, body: "@claude fix", want: false},
+			},
+		},
+		{
+			name: "force processes a repeat",
+			claims: []triggerClaim{
+				{repository: "org/app", issueNumber: 1, commentID: 10, body: "@claude fix", want: true},
+				{repository: "org/app", issueNumber: 1, commentID: 10, body: "@claude fix", force: true, want: true},
+			},
+		},
+		{
+			name: "an edited comment is a new trigger",
+			claims: []triggerClaim{
+				{repository: "org/app", issueNumber: 1, commentID: 10, body: "@claude fix", want: true},
+				{repository: "org/app", issueNumber: 1, commentID: 10, body: "@claude fix the tests", want: true},
+				{repository: "org/app", issueNumber: 1, commentID: 10, body: "@claude fix", want: false},
+			},
+		},
+		{
+			name: "issue body, comments, issues and repositories are distinct",
+			claims: []triggerClaim{
+				{repository: "org/app", issueNumber: 1, body: "@claude fix", want: true},
+				{repository: "org/app", issueNumber: 1, commentID: 10, body: "@claude fix", want: true},
+				{repository: "org/app", issueNumber: 2, body: "@claude fix", want: true},
+				{repository: "org/lib", issueNumber: 1, body: "@claude fix", want: true},
+				{repository: "org/app", issueNumber: 1, body: "@claude fix", want: false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fileStateBackend{path: filepath.Join(t.TempDir(), "state", "state.json")}
+			store, err := NewStateStore(backend)
+			if err != nil {
+				t.Fatalf("NewStateStore failed: %v", err)
+			}
+
+			for i, c := range tt.claims {
+				if got := store.ClaimTrigger(c.repository, c.issueNumber, c.commentID, c.body, c.force); got != c.want {
+					t.Errorf("claim %d: ClaimTrigger(%s#%d, %d, %q, %v) = %v, want %v",
+						i, c.repository, c.issueNumber, c.commentID, c.body, c.force, got, c.want)
+				}
+			}
+
+			// Claimed triggers are persisted and survive a restart
+			reloaded, err := NewStateStore(backend)
+			if err != nil {
+				t.Fatalf("NewStateStore after restart failed: %v", err)
+			}
+			for i, c := range tt.claims {
+				if reloaded.ClaimTrigger(c.repository, c.issueNumber, c.commentID, c.body, false) {
+					t.Errorf("claim %d: ClaimTrigger after restart = true, want false", i)
+				}
+			}
+		})
+	}
+}
+
+func TestClaimTriggerPrunesExpiredTriggers(t *testing.T) {
+	store, err := NewStateStore(&fileStateBackend{path: filepath.Join(t.TempDir(), "state.json")})
+	if err != nil {
+		t.Fatalf("NewStateStore failed: %v", err)
+	}
+
+	store.ClaimTrigger("org/app", 1, 10, "@claude fix", false)
+	key := triggerKey("org/app", 1, 10, hashBody("@claude fix"))
+	expired := store.state.Triggers[key]
+	expired.ProcessedAt = time.Now().Add(-triggerRetention - time.Hour)
+	store.state.Triggers[key] = expired
+
+	store.ClaimTrigger("org/app", 2, 20, "@claude other", false)
+	if _, ok := store.state.Triggers[key]; ok {
+		t.Errorf("trigger older than %v was not pruned", triggerRetention)
+	}
+	if !store.ClaimTrigger("org/app", 1, 10, "@claude fix", false) {
+		t.Errorf("ClaimTrigger of a pruned trigger = false, want true")
+	}
+}
+
+func TestStateStorePrunesInactiveIssues(t *testing.T) {
+	backend := &fileStateBackend{path: filepath.Join(t.TempDir(), "state.json")}
+	store, err := NewStateStore(backend)
+	if err != nil {
+		t.Fatalf("NewStateStore failed: %v", err)
+	}
+
+	store.RecordCommentID("org/app", 1, 10)
+	store.RecordRequest(&IssueRequest{IssueNumber: 1, Task: "fix", SourceRepository: "org/app"})
+	store.state.IssueActivity[issueKey("org/app", 1)] = time.Now().Add(-triggerRetention - time.Hour)
+
+	store.RecordRequest(&IssueRequest{IssueNumber: 2, Task: "other", SourceRepository: "org/app"})
+	if _, found := store.LastRequest("org/app", 1); found {
+		t.Errorf("last request of an issue inactive for longer than %v was not pruned", triggerRetention)
+	}
+	if id := store.LastCommentID("org/app", 1); id != 0 {
+		t.Errorf("LastCommentID of a pruned issue = %d, want 0", id)
+	}
+	if _, found := store.LastRequest("org/app", 2); !found {
+		t.Error("last request of an active issue was pruned")
+	}
+
+	// Issues from state saved without activity are kept after a restart
+	delete(store.state.IssueActivity, issueKey("org/app", 2))
+	if err := store.saveLocked(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewStateStore(backend)
+	if err != nil {
+		t.Fatalf("NewStateStore after restart failed: %v", err)
+	}
+	reloaded.pruneLocked()
+	if _, found := reloaded.LastRequest("org/app", 2); !found {
+		t.Error("last request without recorded activity was pruned on load")
+	}
+}
+
+// countingBackend counts the saves of a fileStateBackend
+type countingBackend struct {
+	fileStateBackend
+	saves int
+}
+
+func (b *countingBackend) Save(data []byte) error {
+	b.saves++
+	return b.fileStateBackend.Save(data)
+}
+
+func TestAdvanceCursorSkipsUnchangedCursor(t *testing.T) {
+	backend := &countingBackend{fileStateBackend: fileStateBackend{path: filepath.Join(t.TempDir(), "state.json")}}
+	store, err := NewStateStore(backend)
+	if err != nil {
+		t.Fatalf("NewStateStore failed: %v", err)
+	}
+
+	checked := time.Now()
+	store.AdvanceCursor("org/app", checked)
+	store.AdvanceCursor("org/app", checked)
+	store.AdvanceCursor("org/app", checked.Add(-time.Minute))
+	if backend.saves != 1 {
+		t.Errorf("got %d saves, want 1 for a cursor that did not move", backend.saves)
+	}
+
+	store.AdvanceCursor("org/app", checked.Add(time.Minute))
+	store.AdvanceCursor("org/lib", checked)
+	if backend.saves != 3 {
+		t.Errorf("got %d saves, want 3 after the cursors moved", backend.saves)
+	}
+}
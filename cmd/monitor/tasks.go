@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// dispatchCommand parses a @claude mention and runs the requested action
+func (m *IssueMonitor) dispatchCommand(ctx context.Context, repo watchedRepository, issue *github.Issue, text, requestedBy string) {
+	issueNumber := issue.GetNumber()
+
+	cmd, err := parseCommand(text)
+	if err != nil {
+		log.Printf("Invalid @claude command on %s#%d: %v", repo.FullName(), issueNumber, err)
+		m.postComment(ctx, repo, issueNumber, fmt.Sprintf("❌ **コマンドを解析できませんでした**\n\n```\n%s\n```\n\n%s", err, commandUsage))
+		return
+	}
+
+	log.Printf("Processing %q command from %s#%d (requested by %s)", cmd.Action, repo.FullName(), issueNumber, requestedBy)
+
+	switch cmd.Action {
+	case actionRun:
+		repository := cmd.Repository
+		if repository == "" {
+			repository = m.detectTargetRepository(repo, issue, cmd.Task)
+		}
+		request := &IssueRequest{
+			IssueNumber:      issueNumber,
+			Task:             cmd.Task,
+			Repository:       repository,
+			SourceRepository: repo.FullName(),
+			Ref:              cmd.Ref,
+			MaxTurns:         maxTurnsOrDefault(cmd.MaxTurns, defaultMaxTurns),
+			RequestedBy:      requestedBy,
+			Labels:           issueLabels(issue),
+		}
+		if !m.authorizeTarget(ctx, repo, request) {
+			return
+		}
+		m.startTask(ctx, repo, request)
+
+	case actionRetry, actionContinue:
+		last, ok := m.state.LastRequest(repo.FullName(), issueNumber)
+		if !ok {
+			m.postComment(ctx, repo, issueNumber, fmt.Sprintf("ℹ️ このIssueには`%s`できるタスクがありません。\n\n%s", cmd.Action, commandUsage))
+			return
+		}
+
+		request := *last
+		request.RequestedBy = requestedBy
+		request.FollowUp = ""
+		request.Labels = issueLabels(issue)
+		request.MaxTurns = maxTurnsOrDefault(cmd.MaxTurns, last.MaxTurns)
+		if cmd.Repository != "" {
+			request.Repository = cmd.Repository
+		}
+		if cmd.Ref != "" {
+			request.Ref = cmd.Ref
+		}
+		if cmd.Action == actionContinue {
+			// The stored session is resumed with the follow-up alone; the
+			// combined task is the fallback when there is no session
+			request.FollowUp = cmd.Task
+			request.Task = fmt.Sprintf("Continue the previous task on this issue.\n\nPrevious task:\n%s\n\nFollow-up request:\n%s", last.Task, cmd.Task)
+		}
+		if !m.authorizeTarget(ctx, repo, &request) {
+			return
+		}
+		m.startTask(ctx, repo, &request)
+
+	case actionCancel:
+		m.cancelTask(ctx, repo, issueNumber, requestedBy)
+
+	case actionStatus:
+		m.postStatus(ctx, repo, issueNumber)
+	}
+}
+
+// maxTurnsOrDefault returns turns unless it is unset
+func maxTurnsOrDefault(turns, fallback int) int {
+	if turns > 0 {
+		return turns
+	}
+	if fallback > 0 {
+		return fallback
+	}
+	return defaultMaxTurns
+}
+
+// startTask queues a request; tasks of the same issue run one after another
+func (m *IssueMonitor) startTask(ctx context.Context, repo watchedRepository, request *IssueRequest) {
+	position, err := m.queue.Enqueue(repo, request)
+	if err != nil {
+		log.Printf("Failed to queue task for %s#%d: %v", repo.FullName(), request.IssueNumber, err)
+		m.postComment(ctx, repo, request.IssueNumber, fmt.Sprintf(
+			"⚠️ **タスクを受け付けられませんでした**\n\n```\n%s\n```\n\n`@claude retry` で再依頼してください。", err))
+		return
+	}
+
+	m.state.RecordRequest(request)
+
+	log.Printf("Task: %s", request.Task)
+	log.Printf("Target repository: %s", request.Repository)
+
+	if position > 0 {
+		log.Printf("Queued task for %s#%d at position %d", repo.FullName(), request.IssueNumber, position)
+		m.postComment(ctx, repo, request.IssueNumber, fmt.Sprintf(
+			"⏳ **タスクをキューに追加しました** (待ち順: %d)\n\nワーカーが空き次第、処理を開始します。`@claude status` で状況を確認できます。", position))
+	}
+}
+
+// runQueuedTask executes a task once the work queue starts it
+func (m *IssueMonitor) runQueuedTask(task *queuedTask) {
+	m.triggerOrchestrator(task.ctx, task.repo, &task.request)
+}
+
+// setTaskPod records the worker pod executing an issue's task
+func (m *IssueMonitor) setTaskPod(repo watchedRepository, issueNumber int, podName string) {
+	m.queue.SetPodName(issueKey(repo.FullName(), issueNumber), podName)
+}
+
+// cancelledBy returns who cancelled an issue's task, if it was cancelled
+func (m *IssueMonitor) cancelledBy(repo watchedRepository, issueNumber int) string {
+	return m.queue.CancelledBy(issueKey(repo.FullName(), issueNumber))
+}
+
+// cancelTask cancels the task running for an issue and drops its queued
+// tasks; the executing goroutine cancels it in the orchestrator, which
+// reports the cancellation and removes the worker
+func (m *IssueMonitor) cancelTask(ctx context.Context, repo watchedRepository, issueNumber int, requestedBy string) {
+	running, dropped := m.queue.Cancel(issueKey(repo.FullName(), issueNumber), requestedBy)
+	if !running && dropped == 0 {
+		m.postComment(ctx, repo, issueNumber, "ℹ️ このIssueで実行中・待機中のタスクはありません。")
+		return
+	}
+
+	log.Printf("Cancelling tasks for %s#%d (requested by %s, running: %v, queued: %d)",
+		repo.FullName(), issueNumber, requestedBy, running, dropped)
+	if dropped > 0 {
+		m.postComment(ctx, repo, issueNumber, fmt.Sprintf("🛑 **待機中のタスク %d 件をキャンセルしました** (@%s)", dropped, requestedBy))
+	}
+}
+
+// handleCancelLabel cancels an issue's tasks when the cancel label was added
+// and removes the label again. Adding labels needs triage access to the
+// repository, so the authorization policy is not consulted. labeledBy is
+// looked up from the issue events when the caller does not know it.
+func (m *IssueMonitor) handleCancelLabel(ctx context.Context, repo watchedRepository, issueNumber int, labeledBy string) {
+	if labeledBy == "" {
+		actor, err := m.labelActor(ctx, repo, issueNumber, cancelLabel)
+		if err != nil {
+			log.Printf("Warning: failed to find who labeled %s#%d %s: %v", repo.FullName(), issueNumber, cancelLabel, err)
+		}
+		labeledBy = actor
+	}
+	if labeledBy == "" {
+		labeledBy = "ghost"
+	}
+
+	log.Printf("Found %s label on %s#%d (added by %s)", cancelLabel, repo.FullName(), issueNumber, labeledBy)
+	err := m.callGitHub(ctx, func() (*github.Response, error) {
+		return m.client.Issues.RemoveLabelForIssue(ctx, repo.Owner, repo.Name, issueNumber, cancelLabel)
+	})
+	if err != nil {
+		log.Printf("Warning: failed to remove %s label from %s#%d: %v", cancelLabel, repo.FullName(), issueNumber, err)
+	}
+	m.cancelTask(ctx, repo, issueNumber, labeledBy)
+}
+
+// postStatus reports the running or last task of an issue
+func (m *IssueMonitor) postStatus(ctx context.Context, repo watchedRepository, issueNumber int) {
+	key := issueKey(repo.FullName(), issueNumber)
+	running, ok := m.queue.Running(key)
+	positions := m.queue.Positions(key)
+
+	var b strings.Builder
+	if ok {
+		podName := running.podName
+		if podName == "" {
+			podName = "(作成中)"
+		}
+		fmt.Fprintf(&b, "🔄 **タスクを実行中です**\n\n")
+		fmt.Fprintf(&b, "- **リポジトリ:** %s\n", running.request.Repository)
+		fmt.Fprintf(&b, "- **Pod:** `%s`\n", podName)
+		fmt.Fprintf(&b, "- **経過時間:** %s\n", time.Since(running.startedAt).Round(time.Second))
+		fmt.Fprintf(&b, "- **最大ターン数:** %d\n", running.request.MaxTurns)
+		fmt.Fprintf(&b, "- **依頼者:** @%s\n", running.request.RequestedBy)
+	} else if len(positions) > 0 {
+		fmt.Fprintf(&b, "⏳ **タスクはキューで待機中です**\n\n")
+	} else if last, found := m.state.LastRequest(repo.FullName(), issueNumber); found {
+		fmt.Fprintf(&b, "💤 **実行中のタスクはありません**\n\n")
+		fmt.Fprintf(&b, "直前のタスク (%s, 最大ターン数 %d):\n\n```\n%s\n```\n\n", last.Repository, last.MaxTurns, last.Task)
+		fmt.Fprintf(&b, "`@claude retry` で再実行できます。")
+	} else {
+		fmt.Fprintf(&b, "💤 このIssueではまだタスクが実行されていません。")
+	}
+
+	if len(positions) > 0 {
+		waiting := make([]string, 0, len(positions))
+		for _, position := range positions {
+			waiting = append(waiting, strconv.Itoa(position))
+		}
+		fmt.Fprintf(&b, "\n- **待機中のタスク:** %d 件 (待ち順: %s)\n", len(positions), strings.Join(waiting, ", "))
+	}
+
+	m.postComment(ctx, repo, issueNumber, b.String())
+}
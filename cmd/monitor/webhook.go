@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// maxWebhookPayload caps the size of a webhook delivery body (GitHub's limit is 25MB)
+const maxWebhookPayload = 25 << 20
+
+// startHTTPServer serves health and metrics endpoints and, in webhook mode, the GitHub
+// webhook receiver. The server is shut down when ctx is cancelled.
+func (m *IssueMonitor) startHTTPServer(ctx context.Context) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", m.healthHandler)
+	mux.HandleFunc("/ready", m.healthHandler)
+	mux.Handle("/metrics", m.metrics)
+	if m.mode == modeWebhook {
+		mux.Handle("/webhook", &webhookHandler{monitor: m, secret: m.webhookSecret, ctx: ctx})
+	}
+
+	server := &http.Server{
+		Addr:              m.listenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("HTTP server listening on %s", m.listenAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("HTTP server error: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	return server
+}
+
+func (m *IssueMonitor) healthHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]string{
+		"status": "healthy",
+		"mode":   m.mode,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// webhookHandler receives GitHub webhook deliveries and feeds them into the
+// same handlers used by the polling loop
+type webhookHandler struct {
+	monitor *IssueMonitor
+	secret  []byte
+	ctx     context.Context
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayload))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	deliveryID := github.DeliveryID(r)
+	signature := r.Header.Get(github.SHA256SignatureHeader)
+	if signature == "" {
+		log.Printf("Rejected webhook delivery %s: missing %s header", deliveryID, github.SHA256SignatureHeader)
+		http.Error(w, "Missing signature", http.StatusUnauthorized)
+		return
+	}
+	if err := github.ValidateSignature(signature, payload, h.secret); err != nil {
+		log.Printf("Rejected webhook delivery %s: %v", deliveryID, err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	eventType := github.WebHookType(r)
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		// Unsupported event types are acknowledged so GitHub does not retry them
+		log.Printf("Ignoring webhook delivery %s (%s): %v", deliveryID, eventType, err)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	log.Printf("Received webhook delivery %s (%s)", deliveryID, eventType)
+
+	// Respond immediately; GitHub times out deliveries after 10 seconds
+	w.WriteHeader(http.StatusAccepted)
+	go h.dispatch(event)
+}
+
+// dispatch routes a parsed webhook event to the issue or comment handler
+func (h *webhookHandler) dispatch(event interface{}) {
+	m := h.monitor
+	ctx := h.ctx
+
+	switch e := event.(type) {
+	case *github.IssuesEvent:
+		repo, watched := m.isWatchedRepository(e.GetRepo())
+		if !watched {
+			return
+		}
+		switch e.GetAction() {
+		case "opened", "edited", "reopened":
+		case "labeled":
+			if strings.EqualFold(e.GetLabel().GetName(), cancelLabel) {
+				m.handleCancelLabel(ctx, repo, e.GetIssue().GetNumber(), e.GetSender().GetLogin())
+				return
+			}
+			// Only the re-run label triggers processing of an existing mention
+			if !strings.EqualFold(e.GetLabel().GetName(), rerunLabel) {
+				return
+			}
+		default:
+			return
+		}
+		issue := e.GetIssue()
+		if issue == nil || issue.Body == nil || !m.hasClauldeMention(*issue.Body) {
+			return
+		}
+		log.Printf("Found @claude mention in %s#%d: %s", repo.FullName(), issue.GetNumber(), issue.GetTitle())
+		m.handleIssue(ctx, repo, issue)
+
+	case *github.IssueCommentEvent:
+		repo, watched := m.isWatchedRepository(e.GetRepo())
+		if !watched {
+			return
+		}
+		switch e.GetAction() {
+		case "created", "edited":
+		default:
+			return
+		}
+		issue, comment := e.GetIssue(), e.GetComment()
+		if issue == nil || comment == nil || comment.Body == nil || !m.hasClauldeMention(*comment.Body) {
+			return
+		}
+		log.Printf("Found @claude mention in comment on %s#%d", repo.FullName(), issue.GetNumber())
+		m.handleIssueComment(ctx, repo, issue, comment)
+
+	case *github.PullRequestReviewCommentEvent:
+		repo, watched := m.isWatchedRepository(e.GetRepo())
+		if !watched {
+			return
+		}
+		switch e.GetAction() {
+		case "created", "edited":
+		default:
+			return
+		}
+		pr, reviewComment := e.GetPullRequest(), e.GetComment()
+		if pr == nil || reviewComment == nil || reviewComment.Body == nil || !m.hasClauldeMention(*reviewComment.Body) {
+			return
+		}
+		log.Printf("Found @claude mention in review comment on %s#%d", repo.FullName(), pr.GetNumber())
+
+		// Pull requests are issues in the GitHub API, so replies go through the
+		// same issue comment path
+		issue := &github.Issue{
+			Number: pr.Number,
+			Title:  pr.Title,
+			Body:   pr.Body,
+			Labels: pr.Labels,
+			User:   pr.User,
+		}
+		comment := &github.IssueComment{
+			ID:                reviewComment.ID,
+			Body:              reviewComment.Body,
+			User:              reviewComment.User,
+			HTMLURL:           reviewComment.HTMLURL,
+			AuthorAssociation: reviewComment.AuthorAssociation,
+		}
+		m.handleIssueComment(ctx, repo, issue, comment)
+	}
+}
+
+// isWatchedRepository reports whether a webhook event belongs to a watched
+// repository. Org patterns are matched directly, so repositories created after
+// startup are accepted without re-listing the organization.
+func (m *IssueMonitor) isWatchedRepository(repo *github.Repository) (watchedRepository, bool) {
+	if repo == nil {
+		return watchedRepository{}, false
+	}
+	if !m.repositories.Matches(repo.GetFullName()) {
+		log.Printf("Ignoring webhook event for unwatched repository %s", repo.GetFullName())
+		return watchedRepository{}, false
+	}
+	return watchedRepository{Owner: repo.GetOwner().GetLogin(), Name: repo.GetName()}, true
+}
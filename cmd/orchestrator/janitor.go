@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/claude-automation/pkg/kubernetes"
+)
+
+// runJanitor removes worker pods that outlived their task every
+// cleanupPeriod until ctx is done
+func (s *Server) runJanitor(ctx context.Context) {
+	if s.orchestrator.podManager == nil || s.cleanupPeriod <= 0 {
+		return
+	}
+	log.Printf("Cleaning up worker pods every %v (max pod age %v)", s.cleanupPeriod, s.maxPodAge)
+
+	ticker := time.NewTicker(s.cleanupPeriod)
+	defer ticker.Stop()
+	for {
+		s.collectGarbage(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectGarbage removes the orchestrator's worker pods that are older than
+// maxPodAge or belong to closed issues and that no running task uses. Their
+// auth secrets are owned by them and garbage-collected with them.
+func (s *Server) collectGarbage(ctx context.Context) {
+	if err := s.orchestrator.podManager.CleanupStalePods(ctx, s.maxPodAge, s.workerInUse); err != nil {
+		log.Printf("Warning: failed to cleanup stale pods: %v", err)
+	}
+	s.removeClosedIssuePods(ctx)
+}
+
+// removeClosedIssuePods removes the worker pods of closed issues that no
+// running task uses
+func (s *Server) removeClosedIssuePods(ctx context.Context) {
+	podManager := s.orchestrator.podManager
+	closed := make(map[string]bool)
+	for _, worker := range podManager.ManagedPods() {
+		if s.workerInUse(worker) {
+			continue
+		}
+		key := fmt.Sprintf("%s#%d", worker.IssueRepository, worker.IssueNumber)
+		isClosed, checked := closed[key]
+		if !checked {
+			var err error
+			if isClosed, err = s.orchestrator.issueClosed(ctx, worker.IssueRepository, worker.IssueNumber); err != nil {
+				log.Printf("Warning: failed to check whether %s is closed: %v", key, err)
+				continue
+			}
+			closed[key] = isClosed
+		}
+		if !isClosed {
+			continue
+		}
+		if err := podManager.DeleteWorker(ctx, worker, "issue is closed"); err != nil {
+			log.Printf("Failed to cleanup pod %s of closed issue %s: %v", worker.PodName, key, err)
+		}
+	}
+}
+
+// workerInUse reports whether a task that has not finished works on the
+// worker's issue
+func (s *Server) workerInUse(worker *kubernetes.WorkerPod) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, run := range s.tasks {
+		task := run.snapshot()
+		source := task.Request.SourceRepository
+		if source == "" {
+			source = s.orchestrator.owner + "/" + s.orchestrator.repo
+		}
+		if !task.State.Done() && task.Request.IssueNumber == worker.IssueNumber && strings.EqualFold(source, worker.IssueRepository) {
+			return true
+		}
+	}
+	return false
+}
+
+// issueClosed reports whether an issue or pull request is closed
+func (o *Orchestrator) issueClosed(ctx context.Context, repository string, issueNumber int) (bool, error) {
+	owner, name := o.splitRepository(repository)
+	issue, _, err := o.githubClient.Issues.Get(ctx, owner, name, issueNumber)
+	if err != nil {
+		return false, fmt.Errorf("failed to get issue: %w", err)
+	}
+	return issue.GetState() == "closed", nil
+}
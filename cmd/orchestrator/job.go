@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/claude-automation/pkg/claude"
+	"github.com/claude-automation/pkg/config"
+	"github.com/claude-automation/pkg/kubernetes"
+	"github.com/claude-automation/pkg/workspace"
+)
+
+// executeInJob runs the execution as a worker Job that clones the
+// repository, runs Claude and pushes the changes by itself, then opens the
+// pull request and posts the summary. Sessions cannot be restored into a
+// Job, so follow-ups start a new one.
+func (o *Orchestrator) executeInJob(ctx context.Context, execution *TaskExecution, timeouts config.TaskTimeouts, statusCommentID int64, run *taskRun) (*claude.TaskResult, error) {
+	issueNumber := execution.IssueNumber
+
+	// Comments and cleanup must still go through after the task is cancelled
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	// Resolve the ref here; the worker only clones it
+	execution.WorkspaceDir = execution.RepoConfig.Workspace
+	err := o.resolveCheckout(ctx, execution)
+	var cloneOpts workspace.CloneOptions
+	if err == nil {
+		cloneOpts, err = workspace.CloneOptionsFromEnv(execution.Repository, execution.Ref, execution.WorkspaceDir)
+	}
+	if err != nil {
+		log.Printf("Failed to resolve the checkout for issue #%d: %v", issueNumber, err)
+		if timeout := config.TimedOut(ctx); timeout != nil {
+			o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, claude.TimeoutMarkdown(timeout, nil, nil, err.Error()))
+			return nil, timeout
+		}
+		if o.reportCancelled(ctx, execution, run, "") {
+			return nil, ctx.Err()
+		}
+		o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, cloneFailedMarkdown(execution, err.Error()))
+		return nil, err
+	}
+
+	claudeCommand := fmt.Sprintf("claude --print --max-turns %d --verbose --output-format %s %s",
+		execution.MaxTurns, execution.OutputFormat, workspace.ShellQuote(o.buildPodTaskContext(execution, execution.WorkspaceDir)))
+	publish := workspace.NewPublishOptions(execution.WorkspaceDir, workspace.IssueBranch(issueNumber),
+		workspace.CommitMessage(execution.issueRef(), execution.Task))
+	script := workspace.TaskScript(cloneOpts, execution.RepoConfig.SetupCommand(), claudeCommand, publish, kubernetes.TerminationMessagePath)
+
+	workerConfig := execution.RepoConfig.Clone()
+	// The clone credential helper in the worker reads GITHUB_TOKEN
+	workerConfig.Env = append(workerConfig.Env, "GITHUB_TOKEN="+os.Getenv("GITHUB_TOKEN"))
+
+	opts := o.jobOptions
+	opts.ActiveDeadline = timeouts[config.PhaseTask]
+	job, err := o.podManager.CreateWorkerJob(ctx, issueNumber, execution.Repository, execution.SourceRepository, workerConfig, script, opts)
+	if err != nil {
+		log.Printf("Failed to create worker job for issue #%d: %v", issueNumber, err)
+		if o.reportCancelled(ctx, execution, run, "") {
+			return nil, ctx.Err()
+		}
+		o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, fmt.Sprintf("❌ **Kubernetes Job作成に失敗しました**\n\n```\n%v\n```", err))
+		return nil, err
+	}
+	log.Printf("Created worker job for issue #%d: %s", issueNumber, job.JobName)
+	execution.WorkerJob = job
+	run.attach(execution)
+
+	// Deleting the Job also stops a worker that is still running
+	defer func() {
+		if err := o.podManager.DeleteWorkerJob(cleanupCtx, job.JobName); err != nil {
+			log.Printf("Failed to cleanup worker job: %v", err)
+		}
+	}()
+
+	// Keep the status comment up to date while Claude is running
+	reportCtx, stopReport := context.WithCancel(ctx)
+	if statusCommentID != 0 {
+		go execution.Progress.Report(reportCtx, o.statusInterval, func(body string) {
+			o.EditIssueComment(cleanupCtx, execution.SourceRepository, statusCommentID, body)
+		})
+	}
+
+	var status *kubernetes.JobStatus
+	output, err := claude.RunStream(execution.Progress, func(stdout io.Writer) error {
+		var err error
+		status, err = o.podManager.FollowWorkerJob(ctx, job.JobName, stdout)
+		return err
+	})
+	execution.Output = output
+	stopReport()
+	if statusCommentID != 0 {
+		o.EditIssueComment(cleanupCtx, execution.SourceRepository, statusCommentID, execution.Progress.Markdown())
+	}
+
+	if ctx.Err() != nil {
+		// Keep what Claude produced before the worker was stopped
+		partial := o.savePartialResult(execution)
+		if timeout := config.TimedOut(ctx); timeout != nil {
+			log.Printf("Task for issue #%d stopped: %v", issueNumber, timeout)
+			o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, claude.TimeoutMarkdown(timeout, execution.Progress, partial, ""))
+			return partial, timeout
+		}
+		if o.reportCancelled(ctx, execution, run, claude.PartialMarkdown(execution.Progress, partial, "")) {
+			return partial, ctx.Err()
+		}
+	}
+	if err != nil {
+		o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, fmt.Sprintf("❌ **エラーが発生しました**\n\n```\n%v\n```", err))
+		return nil, err
+	}
+
+	outcome := workspace.ParseTaskScriptResult(status.TerminationMessage)
+	switch {
+	case outcome.CloneFailed:
+		// The clone wrote its errors to the log ahead of Claude's output
+		err := fmt.Errorf("failed to clone %s@%s in worker job %s", execution.Repository, execution.Ref, job.JobName)
+		o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, cloneFailedMarkdown(execution, strings.TrimSpace(output)))
+		return nil, err
+	case status.DeadlineExceeded():
+		partial := o.savePartialResult(execution)
+		timeout := &config.TimeoutError{Phase: config.PhaseTask, Limit: opts.ActiveDeadline}
+		log.Printf("Task for issue #%d stopped: %v", issueNumber, timeout)
+		o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, claude.TimeoutMarkdown(timeout, execution.Progress, partial, ""))
+		return partial, timeout
+	case execution.Progress.Result() == nil:
+		// The CLI exits non-zero for error results such as error_max_turns,
+		// which are reported through the TaskResult instead
+		err := fmt.Errorf("worker job %s failed (exit code %d): %s", job.JobName, status.ExitCode, strings.TrimSpace(status.TerminationMessage))
+		o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, fmt.Sprintf("❌ **エラーが発生しました**\n\n```\n%v\n```", err))
+		return nil, err
+	}
+
+	// Keep the raw transcript as an artifact and post a readable summary
+	result := claude.ResultFromStream(execution.Progress, output)
+	if err := result.SaveTranscript(o.artifactsDir, execution.Repository, issueNumber); err != nil {
+		log.Printf("Warning: failed to save transcript for issue #%d: %v", issueNumber, err)
+	}
+	summary := result.Markdown()
+	if !result.IsError {
+		prLine := ""
+		if outcome.Published {
+			pushCtx, cancelPush := timeouts.WithTimeout(ctx, config.PhasePush)
+			prLine, err = o.openPullRequest(pushCtx, execution, outcome.Publish, result)
+			cancelPush()
+		} else {
+			err = fmt.Errorf("worker job %s did not push the changes: %s", job.JobName, strings.TrimSpace(status.TerminationMessage))
+		}
+		if err != nil {
+			log.Printf("Failed to publish changes for issue #%d: %v", issueNumber, err)
+			prLine = fmt.Sprintf("⚠️ **変更のPushまたはPull Request作成に失敗しました**\n\n```\n%v\n```", err)
+		}
+		if prLine != "" {
+			summary = prLine + "\n\n" + summary
+		}
+	}
+	o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, summary)
+	log.Printf("Task completed for issue #%d (subtype: %s, turns: %d, cost: $%.4f)",
+		issueNumber, result.Subtype, result.NumTurns, result.CostUSD)
+	return result, nil
+}
@@ -1,19 +1,28 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/claude-automation/pkg/claude"
+	"github.com/claude-automation/pkg/config"
 	"github.com/claude-automation/pkg/container"
 	"github.com/claude-automation/pkg/kubernetes"
+	"github.com/claude-automation/pkg/orchestrator"
+	"github.com/claude-automation/pkg/workspace"
 	"github.com/google/go-github/v57/github"
 	"github.com/joho/godotenv"
 	"golang.org/x/oauth2"
@@ -22,38 +31,42 @@ import (
 type Orchestrator struct {
 	githubClient      *github.Client
 	workspaceRoot     string
-	sessionManager    *SessionManager
+	sessions          *claude.SessionStore
 	containerManager  *container.ContainerManager
 	podManager        *kubernetes.PodManager
+	repoMappings      *config.RepoMappingStore // nil when repo-mapping.yaml could not be loaded
 	owner             string
 	repo              string
 	containerMode     bool
 	kubernetesMode    bool
+	workerMode        string // kubernetes.WorkerModePod or WorkerModeJob
+	jobOptions        kubernetes.JobOptions
+	statusInterval    time.Duration
+	artifactsDir      string
 	mu                sync.Mutex
 }
 
-type SessionManager struct {
-	sessions sync.Map // issue_id -> SessionInfo
-}
-
-type SessionInfo struct {
-	SessionFile string
-	LastUsed    time.Time
-	CreatedAt   time.Time
-}
-
 type TaskExecution struct {
 	IssueID         string
 	IssueNumber     int
 	Task            string
 	Repository      string
-	SessionFile     string
+	SourceRepository string // repository of the issue comments are posted to
+	Ref             string // branch, tag or commit checked out before Claude runs
+	BaseBranch      string // pull request base for the workspace changes
+	WorkspaceDir    string // checkout directory in the pod, container or host
+	RepoConfig      *config.Repository // repo-mapping entry of Repository; nil when there is none
+	PullRequestURL  string // set once the workspace changes are published
+	SessionID       string // Claude session resumed with --resume
 	MaxTurns        int
 	OutputFormat    string
 	UseContainer    bool
 	UseKubernetes   bool
 	WorkerContainer *container.WorkerContainer
 	WorkerPod       *kubernetes.WorkerPod
+	WorkerJob       *kubernetes.WorkerJob // set instead of WorkerPod in the Job worker mode
+	Progress        *claude.Progress // fed from the stream-json output
+	Output          string // raw Claude output, kept when the run fails
 }
 
 func NewOrchestrator() (*Orchestrator, error) {
@@ -89,8 +102,18 @@ func NewOrchestrator() (*Orchestrator, error) {
 	// Workspace setup (Pod内完結型では不要、レガシー互換性のためのみ保持)
 	workspaceRoot := "/tmp/orchestrator-workspace" // ホスト依存を削除
 	
-	// Sessions setup (Pod内完結型では不要、レガシー互換性のためのみ保持)  
-	sessionsDir := "/tmp/orchestrator-sessions" // ホスト依存を削除
+	// Claude sessions are stored here between runs so follow-ups on an issue
+	// can resume them in a new worker
+	sessionsDir := os.Getenv("SESSIONS_DIR")
+	if sessionsDir == "" {
+		sessionsDir = "/tmp/orchestrator-sessions"
+	}
+	sessions, err := claude.NewSessionStore(sessionsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	repoMappingPath := config.RepoMappingPath()
 
 	// Container manager setup
 	containerMode := os.Getenv("CONTAINER_MANAGER_MODE") == "docker"
@@ -99,8 +122,7 @@ func NewOrchestrator() (*Orchestrator, error) {
 	var podManager *kubernetes.PodManager
 	
 	if containerMode {
-		configPath := filepath.Join(".", "config", "repo-mapping.yaml")
-		cm, err := container.NewContainerManager(configPath, workspaceRoot, sessionsDir)
+		cm, err := container.NewContainerManager(repoMappingPath, workspaceRoot, sessionsDir)
 		if err != nil {
 			log.Printf("Warning: Failed to create container manager: %v", err)
 			containerMode = false
@@ -122,75 +144,140 @@ func NewOrchestrator() (*Orchestrator, error) {
 			log.Printf("Warning: Failed to create pod manager: %v", err)
 			kubernetesMode = false
 		} else {
+			pm.SetManager("orchestrator")
+
+			// Setup ServiceAccount and RBAC
+			if err := pm.SetupServiceAccount(ctx); err != nil {
+				log.Printf("Warning: Failed to setup ServiceAccount: %v", err)
+			}
 			podManager = pm
 			log.Println("Kubernetes pod manager initialized successfully")
 		}
 	}
 
+	// Worker images, env, commands, limits and security per repository,
+	// reloaded while the service runs
+	repoMappings, err := config.NewRepoMappingStore(ctx, repoMappingSource(podManager), func(mapping *config.RepoMapping) []error {
+		return kubernetes.CheckResourceLimits(mapping.ResourceLimits)
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to load repository mapping, using built-in defaults: %v", err)
+		repoMappings = nil
+	}
+	if repoMappings != nil && containerManager != nil {
+		containerManager.SetRepoMapping(repoMappings.Current())
+		repoMappings.OnChange(containerManager.SetRepoMapping)
+	}
+	if repoMappings != nil && podManager != nil {
+		podManager.SetRepoMapping(repoMappings.Current())
+		repoMappings.OnChange(podManager.SetRepoMapping)
+	}
+
+	// Kubernetes workers run as pods the orchestrator execs into, or as Jobs
+	// that run the whole task by themselves
+	workerMode, err := kubernetes.WorkerModeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	jobOptions, err := kubernetes.JobOptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// How often the status comment is edited while Claude is running
+	statusInterval := 15 * time.Second
+	if value := os.Getenv("STATUS_UPDATE_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STATUS_UPDATE_INTERVAL %q: %w", value, err)
+		}
+		statusInterval = interval
+	}
+
+	// Raw Claude transcripts are kept here for reviewers
+	artifactsDir := os.Getenv("ARTIFACTS_DIR")
+	if artifactsDir == "" {
+		artifactsDir = "/tmp/orchestrator-artifacts"
+	}
+
 	return &Orchestrator{
 		githubClient:     githubClient,
 		workspaceRoot:    workspaceRoot,
-		sessionManager:   &SessionManager{},
+		sessions:         sessions,
 		containerManager: containerManager,
 		podManager:       podManager,
+		repoMappings:     repoMappings,
 		owner:            owner,
 		repo:             repo,
 		containerMode:    containerMode,
 		kubernetesMode:   kubernetesMode,
+		workerMode:       workerMode,
+		jobOptions:       jobOptions,
+		statusInterval:   statusInterval,
+		artifactsDir:     artifactsDir,
 	}, nil
 }
 
-// ProcessIssueTask processes a GitHub issue with @claude mention
-func (o *Orchestrator) ProcessIssueTask(ctx context.Context, issueNumber int, task string, repository string) error {
+// ProcessIssueTask processes a GitHub issue with @claude mention. Progress is
+// reported by editing the status comment; the header is kept above it.
+func (o *Orchestrator) ProcessIssueTask(ctx context.Context, request orchestrator.TaskRequest, statusCommentID int64, statusHeader string, run *taskRun) (*claude.TaskResult, error) {
+	issueNumber, task, repository := request.IssueNumber, request.Task, request.Repository
 	issueID := strconv.Itoa(issueNumber)
 	log.Printf("Processing issue #%d: %s (repository: %s)", issueNumber, task, repository)
 
-	// Create session for this issue
-	sessionFile, err := o.sessionManager.CreateSession(issueID)
-	if err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
-	}
+	// Comments and cleanup must still go through after the task is cancelled
+	cleanupCtx := context.WithoutCancel(ctx)
+	var err error
 
 	// Create worker container or pod based on mode
 	var workerContainer *container.WorkerContainer
 	var workerPod *kubernetes.WorkerPod
 	useContainer := o.containerMode && o.containerManager != nil
 	useKubernetes := o.kubernetesMode && o.podManager != nil
+
+	// Worker configuration selected by repository, issue labels and language
+	repoConfig, timeouts := o.getRepositoryConfig(ctx, request)
+
+	// Bound the whole task; the clone, Claude run and push have limits of their own
+	ctx, cancelTask := timeouts.WithTimeout(ctx, config.PhaseTask)
+	defer cancelTask()
 	
-	if useKubernetes {
-		// Kubernetes mode - create worker pod with real repo-mapping.yaml config
-		config, err := o.getRepositoryConfig(repository)
-		if err != nil {
-			log.Printf("Failed to load repository config, using default: %v", err)
-			config = &kubernetes.RepositoryConfig{
-				Image:     "worldscandy/claude-automation:k8s", // Default to new integrated image
-				Workspace: "/workspace",
-				Env:       []string{"NODE_ENV=development"},
-			}
-		}
+	// In the Job worker mode the worker runs the whole task by itself
+	useJob := useKubernetes && o.workerMode == kubernetes.WorkerModeJob
+
+	if useKubernetes && !useJob {
+		// Kubernetes mode - create worker pod with the repo-mapping.yaml config
+		config := repoConfig.Clone()
+		// The clone credential helper in the pod reads GITHUB_TOKEN
+		config.Env = append(config.Env, "GITHUB_TOKEN="+os.Getenv("GITHUB_TOKEN"))
 		
-		workerPod, err = o.podManager.CreateWorkerPod(ctx, issueNumber, repository, config)
-		if err != nil {
-			log.Printf("Failed to create worker pod, falling back to host execution: %v", err)
-			useKubernetes = false
-		} else {
+		workerPod, err = o.podManager.CreateWorkerPod(ctx, issueNumber, repository, request.SourceRepository, config)
+		if err == nil {
 			log.Printf("Created worker pod for issue #%d: %s", issueNumber, workerPod.ID)
 			
 			// Wait for pod to be ready
-			if err := o.podManager.WaitForPodReady(ctx, workerPod.PodName, 2*time.Minute); err != nil {
-				log.Printf("Pod failed to become ready, falling back to host execution: %v", err)
-				useKubernetes = false
+			if err = o.podManager.WaitForPodReady(ctx, workerPod.PodName, 2*time.Minute); err != nil {
+				if err := o.podManager.DeleteWorkerPod(cleanupCtx, workerPod.PodName); err != nil {
+					log.Printf("Failed to cleanup worker pod: %v", err)
+				}
 			}
 		}
+		if err != nil {
+			// Never fall back to the host, which has GITHUB_TOKEN and none of
+			// the worker's isolation
+			log.Printf("Worker pod for issue #%d failed: %v", issueNumber, err)
+			return nil, o.reportWorkerFailure(ctx, request, run, "❌ **Kubernetes Pod作成に失敗しました**", err)
+		}
 	} else if useContainer {
 		// Docker mode - create worker container
-		workerContainer, err = o.containerManager.CreateWorkerContainer(ctx, issueNumber, repository)
+		workerContainer, err = o.containerManager.CreateWorkerContainer(ctx, issueNumber, repository, repoConfig.Clone())
 		if err != nil {
-			log.Printf("Failed to create worker container, falling back to host execution: %v", err)
-			useContainer = false
-		} else {
-			log.Printf("Created worker container for issue #%d: %s", issueNumber, workerContainer.ID)
+			// Like a worker pod, a worker container that cannot be created
+			// fails the task instead of running it on the host
+			log.Printf("Worker container for issue #%d failed: %v", issueNumber, err)
+			return nil, o.reportWorkerFailure(ctx, request, run, "❌ **Dockerコンテナ作成に失敗しました**", err)
 		}
+		log.Printf("Created worker container for issue #%d: %s", issueNumber, workerContainer.ID)
 	}
 
 	// Execute task with Claude CLI
@@ -199,45 +286,338 @@ func (o *Orchestrator) ProcessIssueTask(ctx context.Context, issueNumber int, ta
 		IssueNumber:     issueNumber,
 		Task:            task,
 		Repository:      repository,
-		SessionFile:     sessionFile,
-		MaxTurns:        10, // Allow autonomous execution up to 10 turns
-		OutputFormat:    "json",
+		SourceRepository: request.SourceRepository,
+		Ref:             request.Ref,
+		MaxTurns:        request.MaxTurns,
+		OutputFormat:    "stream-json",
 		UseContainer:    useContainer,
 		UseKubernetes:   useKubernetes,
 		WorkerContainer: workerContainer,
 		WorkerPod:       workerPod,
+		RepoConfig:      repoConfig,
+		Progress:        claude.NewProgress(statusHeader),
 	}
+	if execution.Ref == "" {
+		execution.Ref = workspace.RefFromTask(task)
+	}
+	if execution.MaxTurns <= 0 {
+		execution.MaxTurns = 10 // Allow autonomous execution up to 10 turns
+	}
+	if useJob {
+		if request.FollowUp != "" {
+			log.Printf("Job workers cannot resume sessions, issue #%d starts a new one", issueNumber)
+			execution.Task = followUpTask(execution.Task, request.FollowUp)
+			o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, "ℹ️ Kubernetes JobではClaudeのセッションを再開できないため、前回のタスクと追加の依頼をまとめて新しいセッションで実行します。")
+		}
+		return o.executeInJob(ctx, execution, timeouts, statusCommentID, run)
+	}
+	run.attach(execution)
 
 	// Cleanup container or pod when done
 	if useKubernetes && workerPod != nil {
 		defer func() {
-			if err := o.podManager.DeleteWorkerPod(ctx, workerPod.PodName); err != nil {
+			if err := o.podManager.DeleteWorkerPod(cleanupCtx, workerPod.PodName); err != nil {
 				log.Printf("Failed to cleanup worker pod: %v", err)
 			}
 		}()
 	} else if useContainer && workerContainer != nil {
 		defer func() {
-			if err := o.containerManager.StopWorkerContainer(ctx, workerContainer.ID); err != nil {
+			if err := o.containerManager.StopWorkerContainer(cleanupCtx, workerContainer.ID); err != nil {
 				log.Printf("Failed to cleanup worker container: %v", err)
 			}
 		}()
 	}
 
-	result, err := o.ExecuteClaudeTask(ctx, execution)
+	// Check out the target repository so Claude works on real code
+	cloneCtx, cancelClone := timeouts.WithTimeout(ctx, config.PhaseClone)
+	err = o.prepareWorkspace(cloneCtx, execution)
+	cancelClone()
 	if err != nil {
+		log.Printf("Failed to prepare workspace for issue #%d: %v", issueNumber, err)
+		if timeout := config.TimedOut(cloneCtx); timeout != nil {
+			o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, claude.TimeoutMarkdown(timeout, nil, nil, err.Error()))
+			return nil, timeout
+		}
+		if o.reportCancelled(ctx, execution, run, "") {
+			return nil, ctx.Err()
+		}
+		o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, cloneFailedMarkdown(execution, err.Error()))
+		return nil, err
+	}
+
+	// Follow-ups resume the issue's previous session when one is stored
+	if request.FollowUp != "" {
+		o.restoreSession(ctx, execution, request.FollowUp)
+	}
+
+	// Keep the status comment up to date while Claude is running
+	reportCtx, stopReport := context.WithCancel(ctx)
+	if statusCommentID != 0 {
+		go execution.Progress.Report(reportCtx, o.statusInterval, func(body string) {
+			o.EditIssueComment(cleanupCtx, execution.SourceRepository, statusCommentID, body)
+		})
+	}
+
+	claudeCtx, cancelClaude := timeouts.WithTimeout(ctx, config.PhaseClaude)
+	result, err := o.ExecuteClaudeTask(claudeCtx, execution)
+	cancelClaude()
+	stopReport()
+	if claudeCtx.Err() != nil {
+		o.stopClaude(cleanupCtx, execution)
+	}
+	if statusCommentID != 0 {
+		o.EditIssueComment(cleanupCtx, execution.SourceRepository, statusCommentID, execution.Progress.Markdown())
+	}
+	o.saveSession(cleanupCtx, execution)
+	if err != nil {
+		if claudeCtx.Err() != nil {
+			// Keep what Claude produced before it was stopped
+			partial := o.savePartialResult(execution)
+			if timeout := config.TimedOut(claudeCtx); timeout != nil {
+				log.Printf("Task for issue #%d stopped: %v", issueNumber, timeout)
+				o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, claude.TimeoutMarkdown(timeout, execution.Progress, partial, ""))
+				return partial, timeout
+			}
+			if o.reportCancelled(ctx, execution, run, claude.PartialMarkdown(execution.Progress, partial, "")) {
+				return partial, ctx.Err()
+			}
+		}
 		// Post error to issue
-		o.PostToIssue(ctx, issueNumber, fmt.Sprintf("❌ **エラーが発生しました**\n\n```\n%v\n```", err))
+		o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, fmt.Sprintf("❌ **エラーが発生しました**\n\n```\n%v\n```", err))
+		return nil, err
+	}
+
+	// Keep the raw transcript as an artifact and post a readable summary
+	if err := result.SaveTranscript(o.artifactsDir, repository, issueNumber); err != nil {
+		log.Printf("Warning: failed to save transcript for issue #%d: %v", issueNumber, err)
+	}
+	summary := result.Markdown()
+	if !result.IsError {
+		pushCtx, cancelPush := timeouts.WithTimeout(ctx, config.PhasePush)
+		prLine, err := o.publishChanges(pushCtx, execution, result)
+		cancelPush()
+		if err != nil {
+			log.Printf("Failed to publish changes for issue #%d: %v", issueNumber, err)
+			prLine = fmt.Sprintf("⚠️ **変更のPushまたはPull Request作成に失敗しました**\n\n```\n%v\n```", err)
+			if timeout := config.TimedOut(pushCtx); timeout != nil {
+				prLine = claude.TimeoutMarkdown(timeout, nil, nil, err.Error())
+			}
+		}
+		if prLine != "" {
+			summary = prLine + "\n\n" + summary
+		}
+	}
+	o.PostToIssue(cleanupCtx, execution.SourceRepository, issueNumber, summary)
+	log.Printf("Task completed for issue #%d (subtype: %s, turns: %d, cost: $%.4f)",
+		issueNumber, result.Subtype, result.NumTurns, result.CostUSD)
+	return result, nil
+}
+
+// savePartialResult keeps the output of a Claude run that was stopped as a
+// transcript artifact
+func (o *Orchestrator) savePartialResult(execution *TaskExecution) *claude.TaskResult {
+	partial := claude.ResultFromStream(execution.Progress, execution.Output)
+	if execution.Output != "" {
+		if err := partial.SaveTranscript(o.artifactsDir, execution.Repository, execution.IssueNumber); err != nil {
+			log.Printf("Warning: failed to save partial transcript for issue #%d: %v", execution.IssueNumber, err)
+		}
+	}
+	return partial
+}
+
+// stopClaude ends the Claude CLI in the worker after its run was cancelled
+// or timed out. Closing the exec stream leaves it running in a pod or
+// container; on the host the command context already signals it.
+func (o *Orchestrator) stopClaude(ctx context.Context, execution *TaskExecution) {
+	if !(execution.UseKubernetes && execution.WorkerPod != nil) && !(execution.UseContainer && execution.WorkerContainer != nil) {
+		return
+	}
+	if _, err := o.execInWorker(ctx, execution, claude.StopCommand, nil); err != nil {
+		log.Printf("Warning: failed to stop Claude CLI for issue #%d: %v", execution.IssueNumber, err)
+	}
+}
+
+// reportCancelled posts a cancellation comment, followed by details such as
+// the partial output, when ctx was cancelled and reports whether it did
+func (o *Orchestrator) reportCancelled(ctx context.Context, execution *TaskExecution, run *taskRun, details string) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+
+	body := "🛑 **タスクをキャンセルしました**"
+	if by := run.cancelledBy(); by != "" {
+		body += fmt.Sprintf(" (@%s によるキャンセル)", by)
+	}
+	body += "\n" + details
+	log.Printf("Task for issue #%d was cancelled", execution.IssueNumber)
+	o.PostToIssue(context.WithoutCancel(ctx), execution.SourceRepository, execution.IssueNumber, body)
+	return true
+}
+
+// reportWorkerFailure comments why the task's worker pod or container could
+// not be created or did not start under title, with the container states and
+// the pod's events of a PodStartError, and returns the error the task fails with
+func (o *Orchestrator) reportWorkerFailure(ctx context.Context, request orchestrator.TaskRequest, run *taskRun, title string, err error) error {
+	pending := &TaskExecution{IssueNumber: request.IssueNumber, SourceRepository: request.SourceRepository}
+	if timeout := config.TimedOut(ctx); timeout != nil {
+		o.PostToIssue(context.WithoutCancel(ctx), request.SourceRepository, request.IssueNumber, claude.TimeoutMarkdown(timeout, nil, nil, err.Error()))
+		return timeout
+	}
+	if o.reportCancelled(ctx, pending, run, "") {
+		return ctx.Err()
+	}
+
+	var startErr *kubernetes.PodStartError
+	if errors.As(err, &startErr) {
+		title = "❌ **Pod起動に失敗しました**"
+	}
+	o.PostToIssue(ctx, request.SourceRepository, request.IssueNumber, fmt.Sprintf("%s\n\n```\n%v\n```", title, err))
+	return err
+}
+
+// resolveCheckout records the ref prepareWorkspace checks out and the pull
+// request base branch in the execution
+func (o *Orchestrator) resolveCheckout(ctx context.Context, execution *TaskExecution) error {
+	gh := &workspace.GitHub{Client: o.githubClient}
+	checkout, err := gh.ResolveCheckout(ctx, execution.Repository, execution.Ref, execution.IssueNumber)
+	if err != nil {
+		return err
+	}
+	execution.Ref = checkout.Ref
+	execution.BaseBranch = checkout.Base
+	return nil
+}
+
+// cloneFailedMarkdown renders the comment for a checkout that failed
+func cloneFailedMarkdown(execution *TaskExecution, output string) string {
+	return fmt.Sprintf("❌ **リポジトリのクローンに失敗しました**\n\n**Repository:** %s\n**Ref:** %s\n\n```\n%s\n```\n\nリポジトリ名・ブランチ名とトークンの権限を確認してください。",
+		execution.Repository, execution.Ref, output)
+}
+
+// prepareWorkspace clones the execution's repository into the workspace of
+// the pod, container or host it runs on, at the requested ref or the
+// repository's default branch
+func (o *Orchestrator) prepareWorkspace(ctx context.Context, execution *TaskExecution) error {
+	if err := o.resolveCheckout(ctx, execution); err != nil {
 		return err
 	}
 
-	// Post success result to issue
-	o.PostToIssue(ctx, issueNumber, fmt.Sprintf("✅ **タスク完了**\n\n%s", result))
-	log.Printf("Task completed for issue #%d", issueNumber)
+	var dir string
+	switch {
+	case execution.UseKubernetes && execution.WorkerPod != nil:
+		dir = execution.WorkerPod.Config.Workspace
+	case execution.UseContainer && execution.WorkerContainer != nil:
+		dir = execution.WorkerContainer.Config.Workspace
+	default:
+		dir = filepath.Join(o.workspaceRoot, execution.IssueID)
+	}
+	execution.WorkspaceDir = dir
+
+	opts, err := workspace.CloneOptionsFromEnv(execution.Repository, execution.Ref, dir)
+	if err != nil {
+		return err
+	}
+
+	var output string
+	switch {
+	case execution.UseKubernetes && execution.WorkerPod != nil:
+		output, err = o.podManager.CloneRepository(ctx, execution.WorkerPod.PodName, opts)
+	case execution.UseContainer && execution.WorkerContainer != nil:
+		output, err = o.containerManager.CloneRepository(ctx, execution.WorkerContainer.ID, opts)
+	default:
+		cmd := exec.CommandContext(ctx, "sh", "-c", workspace.CloneScript(opts))
+		var out []byte
+		out, err = cmd.CombinedOutput()
+		output = strings.TrimSpace(string(out))
+		if err != nil {
+			err = fmt.Errorf("failed to clone %s@%s: %w\nOutput: %s", opts.Repository, opts.Ref, err, output)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Prepared workspace for issue #%d: %s", execution.IssueNumber, output)
+
+	// Install dependencies etc. with the repository's setup command. Claude
+	// can still work without it, so a failure is only logged.
+	if setup := execution.RepoConfig.SetupCommand(); setup != "" {
+		output, err := o.execInWorker(ctx, execution, fmt.Sprintf("cd %s && %s", workspace.ShellQuote(dir), setup), nil)
+		if err != nil {
+			log.Printf("Warning: setup command failed for issue #%d: %v", execution.IssueNumber, err)
+		} else {
+			log.Printf("Ran setup command for issue #%d: %s", execution.IssueNumber, strings.TrimSpace(output))
+		}
+	}
 	return nil
 }
 
+// publishChanges commits the workspace changes of a successful run to the
+// issue branch, pushes it and opens or updates its pull request. It returns
+// a line for the issue comment, or "" when nothing changed.
+func (o *Orchestrator) publishChanges(ctx context.Context, execution *TaskExecution, result *claude.TaskResult) (string, error) {
+	branch := workspace.IssueBranch(execution.IssueNumber)
+	opts := workspace.NewPublishOptions(execution.WorkspaceDir, branch, workspace.CommitMessage(execution.issueRef(), execution.Task))
+
+	var published workspace.PublishResult
+	var err error
+	switch {
+	case execution.UseKubernetes && execution.WorkerPod != nil:
+		published, err = o.podManager.PublishChanges(ctx, execution.WorkerPod.PodName, opts)
+	case execution.UseContainer && execution.WorkerContainer != nil:
+		published, err = o.containerManager.PublishChanges(ctx, execution.WorkerContainer.ID, opts)
+	default:
+		var out []byte
+		out, err = exec.CommandContext(ctx, "sh", "-c", workspace.PublishScript(opts)).CombinedOutput()
+		if err != nil {
+			err = fmt.Errorf("failed to push %s: %w\nOutput: %s", branch, err, out)
+		} else {
+			published, err = workspace.ParsePublishOutput(string(out))
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return o.openPullRequest(ctx, execution, published, result)
+}
+
+// issueRef refers to the execution's issue from the target repository
+func (e *TaskExecution) issueRef() string {
+	if !strings.EqualFold(e.Repository, e.SourceRepository) {
+		return fmt.Sprintf("%s#%d", e.SourceRepository, e.IssueNumber)
+	}
+	return fmt.Sprintf("#%d", e.IssueNumber)
+}
+
+// openPullRequest opens or updates the pull request of the issue branch
+// pushed by the worker. It returns a line for the issue comment, or "" when
+// nothing changed.
+func (o *Orchestrator) openPullRequest(ctx context.Context, execution *TaskExecution, published workspace.PublishResult, result *claude.TaskResult) (string, error) {
+	if !published.Changed {
+		log.Printf("No workspace changes to publish for issue #%d", execution.IssueNumber)
+		return "", nil
+	}
+
+	branch := workspace.IssueBranch(execution.IssueNumber)
+	gh := &workspace.GitHub{Client: o.githubClient}
+	pr, created, err := gh.EnsurePullRequest(ctx, execution.Repository, branch, execution.BaseBranch,
+		workspace.PullRequestTitle(execution.issueRef(), execution.Task),
+		workspace.PullRequestBody(execution.issueRef(), execution.Task, result.Message))
+	if err != nil {
+		return "", err
+	}
+
+	action := "更新"
+	if created {
+		action = "作成"
+	}
+	execution.PullRequestURL = pr.GetHTMLURL()
+	log.Printf("Pushed %s (%s) and %s pull request %s", branch, published.Commit, action, pr.GetHTMLURL())
+	return fmt.Sprintf("🔀 **Pull Requestを%sしました:** %s (`%s`)", action, pr.GetHTMLURL(), branch), nil
+}
+
 // ExecuteClaudeTask executes a task using advanced Claude CLI features
-func (o *Orchestrator) ExecuteClaudeTask(ctx context.Context, execution *TaskExecution) (string, error) {
+func (o *Orchestrator) ExecuteClaudeTask(ctx context.Context, execution *TaskExecution) (*claude.TaskResult, error) {
 	if execution.UseKubernetes && execution.WorkerPod != nil {
 		return o.executeInPod(ctx, execution)
 	} else if execution.UseContainer && execution.WorkerContainer != nil {
@@ -247,12 +627,12 @@ func (o *Orchestrator) ExecuteClaudeTask(ctx context.Context, execution *TaskExe
 }
 
 // executeOnHost executes Claude CLI on the host system
-func (o *Orchestrator) executeOnHost(ctx context.Context, execution *TaskExecution) (string, error) {
+func (o *Orchestrator) executeOnHost(ctx context.Context, execution *TaskExecution) (*claude.TaskResult, error) {
 	workDir := filepath.Join(o.workspaceRoot, execution.IssueID)
 	
 	// Ensure workspace exists
 	if err := os.MkdirAll(workDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create workspace: %w", err)
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
 	}
 
 	// Prepare Claude CLI command with advanced features
@@ -266,32 +646,43 @@ func (o *Orchestrator) executeOnHost(ctx context.Context, execution *TaskExecuti
 		args = append(args, "--output-format", execution.OutputFormat)
 	}
 	
-	if execution.SessionFile != "" {
-		args = append(args, "--continue", execution.SessionFile)
+	if execution.SessionID != "" {
+		args = append(args, "--resume", execution.SessionID)
 	}
 
 	// Build comprehensive task context
 	taskContext := o.buildTaskContext(execution)
 	
-	// Execute Claude CLI directly (host execution)
+	// Execute Claude CLI directly (host execution), streaming its output
 	claudeCmd := append([]string{"claude"}, args...)
-	cmd := exec.Command(claudeCmd[0], claudeCmd[1:]...)
+	cmd := exec.CommandContext(ctx, claudeCmd[0], claudeCmd[1:]...)
 	cmd.Dir = workDir
 	cmd.Stdin = strings.NewReader(taskContext)
-	
-	output, err := cmd.CombinedOutput()
+	// On cancellation or timeout give Claude a moment to exit before it is killed
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = 10 * time.Second
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := claude.RunStream(execution.Progress, func(stdout io.Writer) error {
+		cmd.Stdout = stdout
+		return cmd.Run()
+	})
+	execution.Output = output
+	if err != nil && execution.Progress.Result() != nil {
+		// The CLI exits non-zero for error results such as error_max_turns,
+		// which are reported through the TaskResult instead
+		err = nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("claude command failed: %w\nOutput: %s", err, output)
+		return nil, fmt.Errorf("claude command failed: %w\nOutput: %s%s", err, output, stderr.String())
 	}
 
-	// Update session usage
-	o.sessionManager.UpdateSessionUsage(execution.IssueID)
-	
-	return string(output), nil
+	return claude.ResultFromStream(execution.Progress, output), nil
 }
 
 // executeInContainer executes Claude CLI inside a worker container
-func (o *Orchestrator) executeInContainer(ctx context.Context, execution *TaskExecution) (string, error) {
+func (o *Orchestrator) executeInContainer(ctx context.Context, execution *TaskExecution) (*claude.TaskResult, error) {
 	containerID := execution.WorkerContainer.ID
 	
 	// Prepare Claude CLI command for container execution
@@ -306,27 +697,31 @@ func (o *Orchestrator) executeInContainer(ctx context.Context, execution *TaskEx
 		claudeArgs = append(claudeArgs, "--output-format", execution.OutputFormat)
 	}
 	
-	if execution.SessionFile != "" {
-		// Map session file to container path
-		sessionPath := "/app/sessions/" + filepath.Base(execution.SessionFile)
-		claudeArgs = append(claudeArgs, "--continue", sessionPath)
+	if execution.SessionID != "" {
+		claudeArgs = append(claudeArgs, "--resume", workspace.ShellQuote(execution.SessionID))
 	}
 
 	// Build task context
 	taskContext := o.buildTaskContext(execution)
 	
-	// Create temporary file with task context
-	tempFile := fmt.Sprintf("/tmp/claude-task-%s.txt", execution.IssueID)
-	createTaskFileCmd := fmt.Sprintf("echo %s > %s", 
-		strings.ReplaceAll(taskContext, `"`, `\"`), tempFile)
-	
-	if _, err := o.containerManager.ExecuteInContainer(ctx, containerID, createTaskFileCmd); err != nil {
-		return "", fmt.Errorf("failed to create task file in container: %w", err)
+	// Write the task context to a file through stdin; it comes from the API
+	// and must never be interpreted by the shell
+	tempFile := workspace.ShellQuote(fmt.Sprintf("/tmp/claude-task-%s.txt", execution.IssueID))
+	if _, err := o.containerManager.ExecuteInContainerWithInput(ctx, containerID, "cat > "+tempFile, strings.NewReader(taskContext)); err != nil {
+		return nil, fmt.Errorf("failed to create task file in container: %w", err)
 	}
 	
 	// Execute Claude CLI in container with task file as input
-	claudeCmd := strings.Join(claudeArgs, " ") + " < " + tempFile
-	output, err := o.containerManager.ExecuteInContainer(ctx, containerID, claudeCmd)
+	claudeCmd := claude.Exec(strings.Join(claudeArgs, " ") + " < " + tempFile)
+	output, err := claude.RunStream(execution.Progress, func(stdout io.Writer) error {
+		return o.containerManager.ExecuteInContainerStream(ctx, containerID, claudeCmd, stdout)
+	})
+	execution.Output = output
+	if err != nil && execution.Progress.Result() != nil {
+		// The CLI exits non-zero for error results such as error_max_turns,
+		// which are reported through the TaskResult instead
+		err = nil
+	}
 	if err != nil {
 		// Get container logs for debugging
 		logs, logErr := o.containerManager.GetContainerLogs(ctx, containerID)
@@ -335,7 +730,7 @@ func (o *Orchestrator) executeInContainer(ctx context.Context, execution *TaskEx
 		} else {
 			log.Printf("Container logs:\n%s", logs)
 		}
-		return "", fmt.Errorf("claude command failed in container: %w\nOutput: %s", err, output)
+		return nil, fmt.Errorf("claude command failed in container: %w\nOutput: %s", err, output)
 	}
 
 	// Cleanup temp file
@@ -344,25 +739,21 @@ func (o *Orchestrator) executeInContainer(ctx context.Context, execution *TaskEx
 		log.Printf("Warning: failed to cleanup temp file: %v", err)
 	}
 
-	// Update session usage
-	o.sessionManager.UpdateSessionUsage(execution.IssueID)
-	
-	return output, nil
+	return claude.ResultFromStream(execution.Progress, output), nil
 }
 
 // executeInPod executes Claude CLI inside a Kubernetes worker pod (Pod内完結型)
-func (o *Orchestrator) executeInPod(ctx context.Context, execution *TaskExecution) (string, error) {
+func (o *Orchestrator) executeInPod(ctx context.Context, execution *TaskExecution) (*claude.TaskResult, error) {
 	podName := execution.WorkerPod.PodName
 	
-	// Pod内完結型: 固定パスを使用
-	workspaceDir := "/workspace"
-	sessionFile := fmt.Sprintf("/tmp/claude/session-%s.json", execution.IssueID)
-	taskFile := fmt.Sprintf("/tmp/claude/task-%s.txt", execution.IssueID)
+	// Pod内完結型: repo-mapping.yaml の workspace を使用
+	workspaceDir := execution.WorkspaceDir
+	taskFile := workspace.ShellQuote(fmt.Sprintf("/tmp/claude/task-%s.txt", execution.IssueID))
 	
 	// Pod内でディレクトリ構造をセットアップ
-	setupCmd := fmt.Sprintf("mkdir -p %s && mkdir -p /tmp/claude && mkdir -p /app/auth", workspaceDir)
+	setupCmd := fmt.Sprintf("mkdir -p %s && mkdir -p /tmp/claude && mkdir -p /app/auth", workspace.ShellQuote(workspaceDir))
 	if _, err := o.podManager.ExecuteInPod(ctx, podName, setupCmd); err != nil {
-		return "", fmt.Errorf("failed to setup pod workspace: %w", err)
+		return nil, fmt.Errorf("failed to setup pod workspace: %w", err)
 	}
 	
 	// Prepare Claude CLI command for pod execution
@@ -377,21 +768,31 @@ func (o *Orchestrator) executeInPod(ctx context.Context, execution *TaskExecutio
 		claudeArgs = append(claudeArgs, "--output-format", execution.OutputFormat)
 	}
 	
-	// Session management (Pod内パス)
-	claudeArgs = append(claudeArgs, "--continue", sessionFile)
+	// Resume the issue's previous session restored into the pod
+	if execution.SessionID != "" {
+		claudeArgs = append(claudeArgs, "--resume", workspace.ShellQuote(execution.SessionID))
+	}
 
 	// Build task context (Pod内完結型)
 	taskContext := o.buildPodTaskContext(execution, workspaceDir)
 	
-	// Create task file in pod using proper escaping
-	createTaskFileCmd := fmt.Sprintf("cat > %s << 'EOF'\n%s\nEOF", taskFile, taskContext)
-	if _, err := o.podManager.ExecuteInPod(ctx, podName, createTaskFileCmd); err != nil {
-		return "", fmt.Errorf("failed to create task file in pod: %w", err)
+	// Write the task context to a file through stdin; it comes from the API
+	// and must never be interpreted by the shell
+	if _, err := o.podManager.ExecuteInPodWithInput(ctx, podName, "cat > "+taskFile, strings.NewReader(taskContext)); err != nil {
+		return nil, fmt.Errorf("failed to create task file in pod: %w", err)
 	}
 	
 	// Execute Claude CLI in pod with task file as input
-	claudeCmd := fmt.Sprintf("cd %s && %s < %s", workspaceDir, strings.Join(claudeArgs, " "), taskFile)
-	output, err := o.podManager.ExecuteInPod(ctx, podName, claudeCmd)
+	claudeCmd := fmt.Sprintf("cd %s && %s", workspace.ShellQuote(workspaceDir), claude.Exec(strings.Join(claudeArgs, " ")+" < "+taskFile))
+	output, err := claude.RunStream(execution.Progress, func(stdout io.Writer) error {
+		return o.podManager.ExecuteInPodStream(ctx, podName, claudeCmd, stdout)
+	})
+	execution.Output = output
+	if err != nil && execution.Progress.Result() != nil {
+		// The CLI exits non-zero for error results such as error_max_turns,
+		// which are reported through the TaskResult instead
+		err = nil
+	}
 	if err != nil {
 		// Get pod logs for debugging
 		logs, logErr := o.podManager.GetPodLogs(ctx, podName)
@@ -400,7 +801,7 @@ func (o *Orchestrator) executeInPod(ctx context.Context, execution *TaskExecutio
 		} else {
 			log.Printf("Pod logs:\n%s", logs)
 		}
-		return "", fmt.Errorf("claude command failed in pod: %w\nOutput: %s", err, output)
+		return nil, fmt.Errorf("claude command failed in pod: %w\nOutput: %s", err, output)
 	}
 
 	// Cleanup temp files
@@ -409,14 +810,17 @@ func (o *Orchestrator) executeInPod(ctx context.Context, execution *TaskExecutio
 		log.Printf("Warning: failed to cleanup temp file: %v", err)
 	}
 
-	// Update session usage (Pod内管理)
-	o.sessionManager.UpdateSessionUsage(execution.IssueID)
-	
-	return output, nil
+	return claude.ResultFromStream(execution.Progress, output), nil
 }
 
+
+
 // buildTaskContext creates comprehensive context for Claude CLI (Legacy Host版)
 func (o *Orchestrator) buildTaskContext(execution *TaskExecution) string {
+	// A resumed session already has the context of the issue
+	if execution.SessionID != "" {
+		return execution.Task
+	}
 	return fmt.Sprintf(`## GitHub Issue Automation Context
 
 You are Claude Code automating GitHub issue processing. Your task is to autonomously complete the following request.
@@ -439,14 +843,20 @@ You are Claude Code automating GitHub issue processing. Your task is to autonomo
 
 ### Workspace: %s
 
-Begin processing this task autonomously. Use --continue if you need multiple conversation turns.`,
+%s
+Begin processing this task autonomously.`,
 		execution.IssueID,
 		execution.Task,
-		filepath.Join(o.workspaceRoot, execution.IssueID))
+		filepath.Join(o.workspaceRoot, execution.IssueID),
+		execution.RepoConfig.CommandsHint())
 }
 
 // buildPodTaskContext creates comprehensive context for Claude CLI (Pod内完結型)
 func (o *Orchestrator) buildPodTaskContext(execution *TaskExecution, workspaceDir string) string {
+	// A resumed session already has the context of the issue
+	if execution.SessionID != "" {
+		return execution.Task
+	}
 	return fmt.Sprintf(`## Kubernetes Pod GitHub Issue Automation Context
 
 You are Claude Code running inside a Kubernetes Pod, automating GitHub issue processing. 
@@ -458,7 +868,6 @@ You are Claude Code running inside a Kubernetes Pod, automating GitHub issue pro
 
 ### 🐳 Pod Environment:
 - **Workspace**: %s (Pod内固定パス)
-- **Session File**: /tmp/claude/session-%s.json
 - **Auth Files**: /app/auth/.claude.json, /app/auth/.claude/.credentials.json
 
 ### 🛠️ Available Tools:
@@ -471,9 +880,9 @@ You are Claude Code running inside a Kubernetes Pod, automating GitHub issue pro
 1. Use TodoWrite to plan your approach
 2. Break down the task into manageable steps
 3. Execute each step using appropriate tools
-4. Work within the Pod's /workspace directory
+4. Work within the Pod's workspace directory
 5. Provide clear progress updates
-6. Use session management for multi-turn conversations
+6. Follow-up comments on the issue resume this session
 
 ### ⚡ Pod Advantages:
 - Isolated execution environment
@@ -481,71 +890,171 @@ You are Claude Code running inside a Kubernetes Pod, automating GitHub issue pro
 - Dedicated workspace and session management
 - Kubernetes native scalability
 
-Begin processing this task autonomously in the Pod environment. Use --continue for session continuity.`,
+%s
+Begin processing this task autonomously in the Pod environment.`,
 		execution.IssueID,
 		execution.Repository,
 		execution.Task,
 		workspaceDir,
-		execution.IssueID)
+		execution.RepoConfig.CommandsHint())
 }
 
-// SessionManager methods (Pod内完結型対応)
-func (sm *SessionManager) CreateSession(issueID string) (string, error) {
-	// Pod内完結型: ホストファイルシステムに依存しない
-	sessionFile := fmt.Sprintf("/tmp/claude/session-%s.json", issueID)
-	
-	sessionInfo := SessionInfo{
-		SessionFile: sessionFile,
-		CreatedAt:   time.Now(),
-		LastUsed:    time.Now(),
+// restoreSession copies the issue's stored Claude session into the worker so
+// the follow-up resumes it. Without a stored session the follow-up runs as a
+// new task.
+func (o *Orchestrator) restoreSession(ctx context.Context, execution *TaskExecution, followUp string) {
+	record, transcript, found, err := o.sessions.Load(execution.SourceRepository, execution.IssueNumber)
+	if err != nil {
+		log.Printf("Warning: failed to load session for issue #%d: %v", execution.IssueNumber, err)
 	}
-	
-	sm.sessions.Store(issueID, sessionInfo)
-	
-	// Pod内でセッションファイルは動的作成されるため、ここでは作成しない
-	log.Printf("Registered session for issue %s: %s (Pod内で動的作成)", issueID, sessionFile)
-	return sessionFile, nil
+	if !found || !claude.ValidSessionID(record.SessionID) {
+		log.Printf("No stored session for issue #%d, starting a new one", execution.IssueNumber)
+		execution.Task = followUpTask(execution.Task, followUp)
+		return
+	}
+
+	script := claude.RestoreSessionScript(execution.WorkspaceDir, record.SessionID)
+	if _, err := o.execInWorker(ctx, execution, script, bytes.NewReader(transcript)); err != nil {
+		log.Printf("Warning: failed to restore session %s: %v", record.SessionID, err)
+		execution.Task = followUpTask(execution.Task, followUp)
+		return
+	}
+
+	log.Printf("Resuming session %s for issue #%d", record.SessionID, execution.IssueNumber)
+	execution.SessionID = record.SessionID
+	execution.Task = followUp
 }
 
-func (sm *SessionManager) UpdateSessionUsage(issueID string) {
-	if sessionInfoVal, ok := sm.sessions.Load(issueID); ok {
-		sessionInfo := sessionInfoVal.(SessionInfo)
-		sessionInfo.LastUsed = time.Now()
-		sm.sessions.Store(issueID, sessionInfo)
+// followUpTask is the task of a follow-up that runs in a new session. The
+// monitor sends a task that already includes the follow-up; API clients may
+// send the follow-up alone.
+func followUpTask(task, followUp string) string {
+	switch {
+	case strings.TrimSpace(task) == "":
+		return followUp
+	case strings.Contains(task, followUp):
+		return task
+	default:
+		return fmt.Sprintf("%s\n\nFollow-up request:\n%s", task, followUp)
 	}
 }
 
-func (sm *SessionManager) GetSession(issueID string) (string, bool) {
-	if sessionInfoVal, ok := sm.sessions.Load(issueID); ok {
-		sessionInfo := sessionInfoVal.(SessionInfo)
-		return sessionInfo.SessionFile, true
+// saveSession stores the session of the finished run so a later follow-up
+// can resume it after the worker is gone
+func (o *Orchestrator) saveSession(ctx context.Context, execution *TaskExecution) {
+	sessionID := execution.Progress.SessionID()
+	if !claude.ValidSessionID(sessionID) {
+		return
+	}
+
+	transcript, err := o.execInWorker(ctx, execution, claude.ReadSessionScript(sessionID), nil)
+	if err != nil {
+		log.Printf("Warning: failed to read session %s: %v", sessionID, err)
+		return
+	}
+
+	record := claude.SessionRecord{
+		SessionID:   sessionID,
+		Repository:  execution.SourceRepository,
+		IssueNumber: execution.IssueNumber,
+		Workspace:   execution.WorkspaceDir,
+	}
+	if err := o.sessions.Save(record, []byte(transcript)); err != nil {
+		log.Printf("Warning: failed to save session %s: %v", sessionID, err)
+	}
+}
+
+// execInWorker runs a shell script in the pod, container or host the
+// execution runs on and returns its output
+func (o *Orchestrator) execInWorker(ctx context.Context, execution *TaskExecution, script string, stdin io.Reader) (string, error) {
+	switch {
+	case execution.UseKubernetes && execution.WorkerPod != nil:
+		if stdin != nil {
+			return o.podManager.ExecuteInPodWithInput(ctx, execution.WorkerPod.PodName, script, stdin)
+		}
+		return o.podManager.ExecuteInPod(ctx, execution.WorkerPod.PodName, script)
+	case execution.UseContainer && execution.WorkerContainer != nil:
+		if stdin != nil {
+			return o.containerManager.ExecuteInContainerWithInput(ctx, execution.WorkerContainer.ID, script, stdin)
+		}
+		return o.containerManager.ExecuteInContainer(ctx, execution.WorkerContainer.ID, script)
+	default:
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", script)
+		cmd.Stdin = stdin
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("failed to execute command: %w\nOutput: %s", err, stderr.String())
+		}
+		return stdout.String(), nil
 	}
-	return "", false
 }
 
-// PostToIssue posts a comment to a GitHub issue
-func (o *Orchestrator) PostToIssue(ctx context.Context, issueNumber int, message string) error {
+// PostToIssue posts a comment to a GitHub issue of repository (owner/name)
+func (o *Orchestrator) PostToIssue(ctx context.Context, repository string, issueNumber int, message string) error {
 	comment := &github.IssueComment{
 		Body: &message,
 	}
 	
-	_, _, err := o.githubClient.Issues.CreateComment(ctx, o.owner, o.repo, issueNumber, comment)
+	owner, name := o.splitRepository(repository)
+	_, _, err := o.githubClient.Issues.CreateComment(ctx, owner, name, issueNumber, comment)
 	if err != nil {
-		log.Printf("Failed to post comment to issue #%d: %v", issueNumber, err)
+		log.Printf("Failed to post comment to issue %s#%d: %v", repository, issueNumber, err)
 		return err
 	}
 	
-	log.Printf("Posted comment to issue #%d", issueNumber)
+	log.Printf("Posted comment to issue %s#%d", repository, issueNumber)
 	return nil
 }
 
-// Integration with monitor - this would be called by the monitor
-func (o *Orchestrator) HandleIssueRequest(ctx context.Context, issueNumber int, task string, repository string) {
-	log.Printf("Received issue processing request: #%d (repository: %s)", issueNumber, repository)
+// CreateIssueComment posts a comment and returns its ID so it can be edited later
+func (o *Orchestrator) CreateIssueComment(ctx context.Context, repository string, issueNumber int, message string) (int64, error) {
+	owner, name := o.splitRepository(repository)
+	comment, _, err := o.githubClient.Issues.CreateComment(ctx, owner, name, issueNumber, &github.IssueComment{
+		Body: &message,
+	})
+	if err != nil {
+		log.Printf("Failed to post comment to issue %s#%d: %v", repository, issueNumber, err)
+		return 0, err
+	}
+	return comment.GetID(), nil
+}
+
+// EditIssueComment replaces the body of an existing comment
+func (o *Orchestrator) EditIssueComment(ctx context.Context, repository string, commentID int64, message string) error {
+	owner, name := o.splitRepository(repository)
+	_, _, err := o.githubClient.Issues.EditComment(ctx, owner, name, commentID, &github.IssueComment{
+		Body: &message,
+	})
+	if err != nil {
+		log.Printf("Failed to edit comment %d: %v", commentID, err)
+	}
+	return err
+}
+
+// splitRepository splits owner/name, falling back to GITHUB_OWNER/GITHUB_REPO
+func (o *Orchestrator) splitRepository(repository string) (string, string) {
+	if owner, name, ok := strings.Cut(repository, "/"); ok && owner != "" && name != "" {
+		return owner, name
+	}
+	return o.owner, o.repo
+}
+
+// HandleIssueRequest acknowledges a task on its issue and processes it. It
+// returns once the task has finished; run may be nil outside the service.
+func (o *Orchestrator) HandleIssueRequest(ctx context.Context, request orchestrator.TaskRequest, run *taskRun) (*claude.TaskResult, error) {
+	if request.SourceRepository == "" {
+		request.SourceRepository = o.owner + "/" + o.repo
+	}
+	issueNumber, repository := request.IssueNumber, request.Repository
+	log.Printf("Received issue processing request: %s#%d (repository: %s)", request.SourceRepository, issueNumber, repository)
 	
 	// Determine execution mode
 	executionMode := "Host"
-	if o.kubernetesMode {
+	if o.kubernetesMode && o.workerMode == kubernetes.WorkerModeJob {
+		executionMode = "Kubernetes Job"
+	} else if o.kubernetesMode {
 		executionMode = "Kubernetes Pod"
 	} else if o.containerMode {
 		executionMode = "Docker Container"
@@ -555,23 +1064,19 @@ func (o *Orchestrator) HandleIssueRequest(ctx context.Context, issueNumber int,
 	acknowledgment := fmt.Sprintf("🤖 **Claude Automation System**\n\nタスクを受信しました。処理を開始します...\n\n**Issue ID:** #%d\n**Repository:** %s\n**Execution Mode:** %s\n**Session:** `issue-%d`\n**Workspace:** `workspaces/issue-%d/`", 
 		issueNumber, repository, executionMode, issueNumber, issueNumber)
 	
-	if err := o.PostToIssue(ctx, issueNumber, acknowledgment); err != nil {
-		log.Printf("Failed to acknowledge task: %v", err)
-		return
+	// The acknowledgment doubles as the status comment edited with progress
+	statusCommentID, err := o.CreateIssueComment(ctx, request.SourceRepository, issueNumber, acknowledgment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acknowledge task: %w", err)
 	}
 	
-	// Process the task asynchronously
-	go func() {
-		if err := o.ProcessIssueTask(ctx, issueNumber, task, repository); err != nil {
-			log.Printf("Failed to process issue #%d: %v", issueNumber, err)
-		}
-	}()
+	return o.ProcessIssueTask(ctx, request, statusCommentID, acknowledgment, run)
 }
 
 func main() {
 	ctx := context.Background()
 	
-	orchestrator, err := NewOrchestrator()
+	o, err := NewOrchestrator()
 	if err != nil {
 		log.Fatal("Failed to create orchestrator:", err)
 	}
@@ -583,62 +1088,97 @@ func main() {
 			log.Fatal("Invalid issue number:", err)
 		}
 		
-		task := os.Args[4]
-		repository := os.Args[6]
-		
-		log.Printf("Processing issue #%d with task: %s (repository: %s)", issueNumber, task, repository)
+		request := orchestrator.TaskRequest{
+			IssueNumber: issueNumber,
+			Task:        os.Args[4],
+			Repository:  os.Args[6],
+		}
+		// "-continue <follow-up>" resumes the issue's previous session
+		if len(os.Args) >= 9 && os.Args[7] == "-continue" {
+			request.FollowUp = os.Args[8]
+		}
 		
-		// Process the issue task
-		orchestrator.HandleIssueRequest(ctx, issueNumber, task, repository)
+		log.Printf("Processing issue #%d with task: %s (repository: %s)", issueNumber, request.Task, request.Repository)
 		
-		// Wait for completion (in real implementation, this would be handled differently)
-		time.Sleep(30 * time.Second)
+		// Ctrl-C cancels the task, stops Claude and removes the worker
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// Process the issue task and wait for it to finish
+		if _, err := o.HandleIssueRequest(ctx, request, nil); err != nil {
+			log.Fatalf("Failed to process issue #%d: %v", issueNumber, err)
+		}
 		return
 	}
 
-	// Default mode: Run as standalone service
+	// Default mode: Run as a long-running HTTP service the monitor submits tasks to
 	log.Println("Starting Orchestrator in service mode...")
-	
-	// Test with a sample issue if no arguments provided
-	issueID := "test-001"
-	
-	// Test Claude CLI integration
-	log.Printf("Testing Claude CLI integration...")
-	
-	execution := &TaskExecution{
-		IssueID:      issueID,
-		IssueNumber:  1,
-		Task:         "Create a simple test file with 'Hello World' content",
-		Repository:   "worldscandy/claude-automation",
-		MaxTurns:     3,
-		OutputFormat: "json",
-		UseContainer: false,
+
+	// Track worker pods, including those left by an earlier run
+	if o.podManager != nil {
+		if err := o.podManager.SyncWorkerPods(ctx); err != nil {
+			log.Printf("Warning: %v", err)
+		}
 	}
 	
-	result, err := orchestrator.ExecuteClaudeTask(ctx, execution)
+	server, err := NewServer(o)
 	if err != nil {
-		log.Printf("Failed to execute Claude task: %v", err)
-	} else {
-		log.Printf("Claude task result:\n%s", result)
+		log.Fatal("Failed to create orchestrator server:", err)
+	}
+	if err := server.Run(ctx); err != nil {
+		log.Fatal("Orchestrator server failed:", err)
 	}
 }
 
-// getRepositoryConfig loads repository configuration from repo-mapping.yaml
-func (o *Orchestrator) getRepositoryConfig(repository string) (*kubernetes.RepositoryConfig, error) {
-	// For now, return the default configuration for claude-automation
-	// TODO: Implement proper YAML parsing
-	if strings.Contains(repository, "claude-automation") {
-		return &kubernetes.RepositoryConfig{
-			Image:     "worldscandy/claude-automation:k8s",
-			Workspace: "/workspace",
-			Env:       []string{"NODE_ENV=development"},
-		}, nil
+// watchRepoMapping applies changes to repo-mapping.yaml until ctx is done,
+// every REPO_MAPPING_RELOAD_INTERVAL (30s; 0 disables). Running tasks keep
+// the configuration they started with.
+func (o *Orchestrator) watchRepoMapping(ctx context.Context) {
+	if o.repoMappings == nil {
+		return
 	}
-	
-	// Default fallback
-	return &kubernetes.RepositoryConfig{
-		Image:     "worldscandy/claude-automation:k8s",
-		Workspace: "/workspace",
-		Env:       []string{"NODE_ENV=development"},
-	}, nil
-}
\ No newline at end of file
+	interval := 30 * time.Second
+	if value := os.Getenv("REPO_MAPPING_RELOAD_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Warning: invalid REPO_MAPPING_RELOAD_INTERVAL %q, not reloading: %v", value, err)
+			return
+		}
+		interval = parsed
+	}
+	if interval <= 0 {
+		return
+	}
+	log.Printf("Watching %s for changes every %v", o.repoMappings.Source(), interval)
+	o.repoMappings.Watch(ctx, interval, nil)
+}
+
+// getRepositoryConfig returns the repo-mapping.yaml configuration selected by
+// the request's repository, labels and language, or the built-in worker
+// image when no entry applies, with the task's timeouts
+func (o *Orchestrator) getRepositoryConfig(ctx context.Context, request orchestrator.TaskRequest) (*kubernetes.RepositoryConfig, config.TaskTimeouts) {
+	target := config.Target{Repository: request.Repository, Labels: request.Labels}
+	// Read once so the whole task uses one version of the mapping
+	mapping := o.repoMappings.Current()
+	if mapping.UsesLanguages() {
+		gh := &workspace.GitHub{Client: o.githubClient}
+		language, err := gh.Language(ctx, request.Repository)
+		if err != nil {
+			log.Printf("Warning: failed to detect the language of %s: %v", request.Repository, err)
+		}
+		target.Language = language
+	}
+
+	repoConfig := mapping.Resolve(target)
+	if repoConfig == nil {
+		log.Printf("No repository config for %s, using the built-in default", request.Repository)
+		repoConfig = &kubernetes.RepositoryConfig{
+			Image: "worldscandy/claude-automation:k8s",
+			Env:   []string{"NODE_ENV=development"},
+		}
+	}
+	if repoConfig.Workspace == "" {
+		repoConfig.Workspace = "/workspace"
+	}
+	return repoConfig, mapping.TaskTimeouts(repoConfig)
+}
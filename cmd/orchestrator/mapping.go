@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/claude-automation/pkg/config"
+	"github.com/claude-automation/pkg/kubernetes"
+)
+
+// repoMappingConfigMapKey is the key of repo-mapping.yaml in the ConfigMap
+const repoMappingConfigMapKey = "repo-mapping.yaml"
+
+// repoMappingSource reads the worker configuration from
+// REPO_MAPPING_CONFIGMAP through the API in Kubernetes mode, or from
+// REPO_MAPPING_FILE
+func repoMappingSource(podManager *kubernetes.PodManager) config.RepoMappingSource {
+	name := os.Getenv("REPO_MAPPING_CONFIGMAP")
+	if name == "" || podManager == nil {
+		return config.FileSource(config.RepoMappingPath())
+	}
+	return config.RepoMappingSource{
+		Name: "configmap/" + name,
+		Read: func(ctx context.Context) ([]byte, error) {
+			value, found, err := podManager.GetConfigMapValue(ctx, name, repoMappingConfigMapKey)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				return nil, fmt.Errorf("configmap %s has no %s key", name, repoMappingConfigMapKey)
+			}
+			return []byte(value), nil
+		},
+	}
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/claude-automation/pkg/claude"
+	"github.com/claude-automation/pkg/orchestrator"
+)
+
+// maxTaskRequest caps the size of a POST /tasks body
+const maxTaskRequest = 1 << 20
+
+// taskRetention is how long finished tasks can still be queried
+const taskRetention = 24 * time.Hour
+
+// taskRun tracks a task submitted to the service
+type taskRun struct {
+	mu        sync.Mutex
+	task      orchestrator.Task
+	result    *claude.TaskResult
+	execution *TaskExecution
+	cancel    context.CancelFunc
+}
+
+// attach records the execution once its worker is chosen
+func (r *taskRun) attach(execution *TaskExecution) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.execution = execution
+	switch {
+	case execution.UseKubernetes && execution.WorkerPod != nil:
+		r.task.Worker = execution.WorkerPod.PodName
+	case execution.UseKubernetes && execution.WorkerJob != nil:
+		r.task.Worker = execution.WorkerJob.JobName
+	case execution.UseContainer && execution.WorkerContainer != nil:
+		r.task.Worker = execution.WorkerContainer.ID
+	default:
+		r.task.Worker = "host"
+	}
+}
+
+// cancelledBy returns who cancelled the task, if anyone did
+func (r *taskRun) cancelledBy() string {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.task.CancelledBy
+}
+
+func (r *taskRun) snapshot() orchestrator.Task {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.task
+}
+
+func (r *taskRun) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.task.State = orchestrator.StateRunning
+	r.task.StartedAt = &now
+}
+
+func (r *taskRun) finish(ctx context.Context, result *claude.TaskResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.task.FinishedAt = &now
+	r.result = result
+	switch {
+	case ctx.Err() != nil:
+		r.task.State = orchestrator.StateCancelled
+	case err != nil:
+		r.task.State = orchestrator.StateFailed
+		r.task.Error = err.Error()
+	case result.IsError:
+		r.task.State = orchestrator.StateFailed
+		r.task.Error = "claude finished with " + result.Subtype
+	default:
+		r.task.State = orchestrator.StateSucceeded
+	}
+}
+
+func (r *taskRun) outcome() orchestrator.TaskResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outcome := orchestrator.TaskResult{
+		ID:    r.task.ID,
+		State: r.task.State,
+		Error: r.task.Error,
+	}
+	if r.result != nil {
+		outcome.Subtype = r.result.Subtype
+		outcome.IsError = r.result.IsError
+		outcome.Message = r.result.Message
+		outcome.NumTurns = r.result.NumTurns
+		outcome.DurationMS = r.result.Duration.Milliseconds()
+		outcome.CostUSD = r.result.CostUSD
+		outcome.SessionID = r.result.SessionID
+	}
+	if r.execution != nil {
+		outcome.PullRequestURL = r.execution.PullRequestURL
+	}
+	return outcome
+}
+
+// Server exposes the orchestrator as an HTTP/JSON API:
+//
+//	POST /tasks               submit a task
+//	GET  /tasks/{id}          task status
+//	POST /tasks/{id}/cancel   cancel a queued or running task
+//	GET  /tasks/{id}/result   outcome of a finished task
+type Server struct {
+	orchestrator *Orchestrator
+	listenAddr   string
+	token        string
+	drainTimeout time.Duration
+	slots        chan struct{} // bounds the number of running tasks
+
+	// The janitor removes leftover worker pods every cleanupPeriod; 0 disables it
+	cleanupPeriod time.Duration
+	maxPodAge     time.Duration
+
+	// Tasks run on their own context so a shutdown can let them finish
+	baseCtx    context.Context
+	baseCancel context.CancelFunc
+
+	mu    sync.Mutex
+	tasks map[string]*taskRun
+	wg    sync.WaitGroup
+}
+
+// NewServer configures the service from ORCHESTRATOR_LISTEN_ADDR,
+// ORCHESTRATOR_API_TOKEN, MAX_WORKERS, DRAIN_TIMEOUT, CLEANUP_INTERVAL and
+// MAX_POD_AGE. Tasks run with
+// GITHUB_TOKEN, so the API requires a token unless it only listens on loopback.
+func NewServer(o *Orchestrator) (*Server, error) {
+	listenAddr := os.Getenv("ORCHESTRATOR_LISTEN_ADDR")
+	if listenAddr == "" {
+		listenAddr = ":8081"
+	}
+
+	token := os.Getenv("ORCHESTRATOR_API_TOKEN")
+	if token == "" {
+		if !loopbackAddr(listenAddr) {
+			return nil, fmt.Errorf("ORCHESTRATOR_API_TOKEN not set (required unless ORCHESTRATOR_LISTEN_ADDR is a loopback address such as 127.0.0.1:8081)")
+		}
+		log.Printf("Warning: ORCHESTRATOR_API_TOKEN not set, accepting unauthenticated requests on %s", listenAddr)
+	}
+
+	maxWorkers := 5
+	if value := os.Getenv("MAX_WORKERS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid MAX_WORKERS %q (expected a positive integer)", value)
+		}
+		maxWorkers = n
+	}
+
+	drainTimeout := 10 * time.Minute
+	if value := os.Getenv("DRAIN_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DRAIN_TIMEOUT %q: %w", value, err)
+		}
+		drainTimeout = timeout
+	}
+
+	cleanupPeriod := time.Hour
+	if value := os.Getenv("CLEANUP_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CLEANUP_INTERVAL %q: %w", value, err)
+		}
+		cleanupPeriod = interval
+	}
+	maxPodAge := 24 * time.Hour
+	if value := os.Getenv("MAX_POD_AGE"); value != "" {
+		age, err := time.ParseDuration(value)
+		if err != nil || age <= 0 {
+			return nil, fmt.Errorf("invalid MAX_POD_AGE %q: must be a positive duration", value)
+		}
+		maxPodAge = age
+	}
+
+	baseCtx, baseCancel := context.WithCancel(context.Background())
+	return &Server{
+		orchestrator:  o,
+		listenAddr:    listenAddr,
+		token:         token,
+		drainTimeout:  drainTimeout,
+		slots:         make(chan struct{}, maxWorkers),
+		cleanupPeriod: cleanupPeriod,
+		maxPodAge:     maxPodAge,
+		baseCtx:       baseCtx,
+		baseCancel:    baseCancel,
+		tasks:         make(map[string]*taskRun),
+	}, nil
+}
+
+// Run serves the API until SIGINT or SIGTERM, then waits up to DRAIN_TIMEOUT
+// for running tasks before cancelling them
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go s.orchestrator.watchRepoMapping(ctx)
+	go s.runJanitor(ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.healthHandler)
+	mux.HandleFunc("POST /tasks", s.authorize(s.submitHandler))
+	mux.HandleFunc("GET /tasks/{id}", s.authorize(s.taskHandler))
+	mux.HandleFunc("POST /tasks/{id}/cancel", s.authorize(s.cancelHandler))
+	mux.HandleFunc("GET /tasks/{id}/result", s.authorize(s.resultHandler))
+
+	server := &http.Server{
+		Addr:              s.listenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Orchestrator API listening on %s", s.listenAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down orchestrator API...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+
+	s.drain()
+	return nil
+}
+
+// drain waits for running tasks, cancelling them after the drain timeout
+func (s *Server) drain() {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-time.After(s.drainTimeout):
+		log.Printf("Drain timeout (%s) exceeded, cancelling running tasks", s.drainTimeout)
+		s.baseCancel()
+	}
+	<-done
+}
+
+// loopbackAddr reports whether a listen address only accepts local connections
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+}
+
+func (s *Server) submitHandler(w http.ResponseWriter, r *http.Request) {
+	var request orchestrator.TaskRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTaskRequest))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid task request: "+err.Error())
+		return
+	}
+	if err := validateTaskRequest(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.SourceRepository == "" {
+		request.SourceRepository = s.orchestrator.owner + "/" + s.orchestrator.repo
+	}
+
+	ctx, cancel := context.WithCancel(s.baseCtx)
+	run := &taskRun{
+		task: orchestrator.Task{
+			ID:        newTaskID(),
+			Request:   request,
+			State:     orchestrator.StateQueued,
+			CreatedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	s.mu.Lock()
+	s.pruneLocked()
+	s.tasks[run.task.ID] = run
+	s.mu.Unlock()
+
+	log.Printf("Accepted task %s for %s#%d (repository: %s)", run.task.ID, request.SourceRepository, request.IssueNumber, request.Repository)
+	s.wg.Add(1)
+	go s.execute(ctx, run)
+
+	writeJSON(w, http.StatusAccepted, run.snapshot())
+}
+
+func (s *Server) execute(ctx context.Context, run *taskRun) {
+	defer s.wg.Done()
+	defer run.cancel()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		run.finish(ctx, nil, ctx.Err())
+		return
+	}
+
+	run.start()
+	result, err := s.orchestrator.HandleIssueRequest(ctx, run.snapshot().Request, run)
+	run.finish(ctx, result, err)
+
+	task := run.snapshot()
+	log.Printf("Task %s finished: %s", task.ID, task.State)
+}
+
+func (s *Server) taskHandler(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, run.snapshot())
+}
+
+func (s *Server) cancelHandler(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	var request orchestrator.CancelRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTaskRequest)).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid cancel request: "+err.Error())
+			return
+		}
+	}
+
+	run.mu.Lock()
+	if run.task.State.Done() {
+		run.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Sprintf("task already %s", run.task.State))
+		return
+	}
+	run.task.CancelledBy = request.CancelledBy
+	run.mu.Unlock()
+
+	log.Printf("Cancelling task %s (requested by %s)", run.task.ID, request.CancelledBy)
+	run.cancel()
+	writeJSON(w, http.StatusAccepted, run.snapshot())
+}
+
+func (s *Server) resultHandler(w http.ResponseWriter, r *http.Request) {
+	run, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	outcome := run.outcome()
+	if !outcome.State.Done() {
+		writeError(w, http.StatusConflict, fmt.Sprintf("task is still %s", outcome.State))
+		return
+	}
+	writeJSON(w, http.StatusOK, outcome)
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (*taskRun, bool) {
+	s.mu.Lock()
+	run, ok := s.tasks[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "task not found")
+	}
+	return run, ok
+}
+
+// pruneLocked forgets tasks that finished more than taskRetention ago
+func (s *Server) pruneLocked() {
+	for id, run := range s.tasks {
+		task := run.snapshot()
+		if task.FinishedAt != nil && time.Since(*task.FinishedAt) > taskRetention {
+			delete(s.tasks, id)
+		}
+	}
+}
+
+func validateTaskRequest(request *orchestrator.TaskRequest) error {
+	switch {
+	case request.IssueNumber <= 0:
+		return errors.New("issue_number must be a positive integer")
+	case strings.TrimSpace(request.Task) == "" && strings.TrimSpace(request.FollowUp) == "":
+		return errors.New("task must not be empty")
+	case strings.Count(request.Repository, "/") != 1:
+		return errors.New("repository must be in owner/name form")
+	case request.SourceRepository != "" && strings.Count(request.SourceRepository, "/") != 1:
+		return errors.New("source_repository must be in owner/name form")
+	case request.MaxTurns < 0:
+		return errors.New("max_turns must not be negative")
+	}
+	return nil
+}
+
+func newTaskID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "task-" + hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, orchestrator.ErrorResponse{Error: message})
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/claude-automation/pkg/config"
+	"github.com/claude-automation/pkg/container"
+	"github.com/claude-automation/pkg/kubernetes"
+	"github.com/claude-automation/pkg/workspace"
+	"sigs.k8s.io/yaml"
+)
+
+const usage = `Usage:
+  repo-config validate [-f repo-mapping.yaml]
+  repo-config render [-f repo-mapping.yaml] -repo owner/name [-issue N] [-labels a,b] [-language Go] [-mode kubernetes|docker]
+
+validate checks the file and exits non-zero when it has problems.
+render prints the Pod manifest or docker run command a task would use.
+The file defaults to REPO_MAPPING_FILE or config/repo-mapping.yaml.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "render":
+		err = runRender(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		os.Exit(1)
+	}
+}
+
+// runValidate parses the mapping strictly and reports every problem found
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	file := flags.String("f", config.RepoMappingPath(), "repo-mapping.yaml to check")
+	flags.Parse(args)
+
+	mapping, err := config.LoadRepoMapping(*file)
+	if err != nil {
+		return err
+	}
+
+	problems := mapping.Check()
+	problems = append(problems, kubernetes.CheckResourceLimits(mapping.ResourceLimits)...)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *file, problem)
+		}
+		return fmt.Errorf("%s has %d problem(s)", *file, len(problems))
+	}
+
+	fmt.Printf("✅ %s: %d repositories, %d organizations, %d rules\n",
+		*file, len(mapping.Repositories), len(mapping.Organizations), len(mapping.Rules))
+	return nil
+}
+
+// runRender prints what a worker for the given repository and issue would
+// be created with
+func runRender(args []string) error {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	file := flags.String("f", config.RepoMappingPath(), "repo-mapping.yaml to render from")
+	repository := flags.String("repo", "", "target repository (owner/name)")
+	issueNumber := flags.Int("issue", 1, "issue number")
+	labels := flags.String("labels", "", "comma separated issue labels")
+	language := flags.String("language", "", "repository language as reported by GitHub")
+	mode := flags.String("mode", "kubernetes", "worker mode: kubernetes or docker")
+	namespace := flags.String("namespace", "claude-automation", "namespace of the Pod")
+	workspaces := flags.String("workspaces", "/tmp/orchestrator-workspace", "host workspace root of docker workers")
+	flags.Parse(args)
+
+	if *repository == "" {
+		return fmt.Errorf("-repo is required")
+	}
+
+	mapping, err := config.LoadRepoMapping(*file)
+	if err != nil {
+		return err
+	}
+
+	target := config.Target{Repository: *repository, Language: *language}
+	for _, label := range strings.Split(*labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			target.Labels = append(target.Labels, label)
+		}
+	}
+
+	repoConfig := mapping.Resolve(target)
+	if repoConfig == nil {
+		return fmt.Errorf("no entry of %s applies to %s and there is no default", *file, *repository)
+	}
+	if repoConfig.Workspace == "" {
+		repoConfig.Workspace = "/workspace"
+	}
+
+	var sources []string
+	for _, layer := range mapping.Layers(target) {
+		sources = append(sources, layer.Source)
+	}
+	fmt.Printf("# %s#%d from %s: %s\n", *repository, *issueNumber, *file, strings.Join(sources, " < "))
+	fmt.Println("# Credentials such as GITHUB_TOKEN are added to env when the worker is created")
+	timeouts := mapping.TaskTimeouts(repoConfig)
+	var limits []string
+	for _, phase := range []config.Phase{config.PhaseTask, config.PhaseClone, config.PhaseClaude, config.PhasePush} {
+		if limit, ok := timeouts[phase]; ok {
+			limits = append(limits, fmt.Sprintf("%s=%v", phase, limit))
+		}
+	}
+	if len(limits) > 0 {
+		fmt.Printf("# Timeouts: %s\n", strings.Join(limits, " "))
+	}
+
+	switch *mode {
+	case "kubernetes":
+		pod := kubernetes.RenderWorkerPod(*namespace, mapping, *issueNumber, *repository, *repository, repoConfig)
+		manifest, err := yaml.Marshal(pod)
+		if err != nil {
+			return fmt.Errorf("failed to encode pod manifest: %w", err)
+		}
+		fmt.Print(string(manifest))
+	case "docker":
+		command := container.RenderDockerRun(mapping, *workspaces, *issueNumber, *repository, repoConfig)
+		quoted := make([]string, len(command))
+		for i, arg := range command {
+			quoted[i] = shellArg(arg)
+		}
+		fmt.Println(strings.Join(quoted, " "))
+	default:
+		return fmt.Errorf("unknown mode %q (expected kubernetes or docker)", *mode)
+	}
+	return nil
+}
+
+var plainArg = regexp.MustCompile(`^[A-Za-z0-9_./:=@%+,-]+$`)
+
+// shellArg quotes an argument only when the shell would need it
+func shellArg(arg string) string {
+	if plainArg.MatchString(arg) {
+		return arg
+	}
+	return workspace.ShellQuote(arg)
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	// ClaudeConfigTemplate is the template used to render ~/.claude.json
+	ClaudeConfigTemplate = ".claude.json.template"
+	// CredentialsTemplate is the template used to render ~/.claude/.credentials.json
+	CredentialsTemplate = ".claude.credentials.json.template"
+)
+
+// templateSearchDirs lists the directories searched for auth templates when
+// CLAUDE_TEMPLATE_DIR is not set
+var templateSearchDirs = []string{".", "/app", "/app/auth"}
+
+// LoadEnvFile loads environment variables from the given file without
+// overriding variables that are already set in the process environment
+func LoadEnvFile(path string) error {
+	if err := godotenv.Load(path); err != nil {
+		return fmt.Errorf("failed to load env file %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadFileBytes reads a generated auth file
+func ReadFileBytes(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// AuthFiles are the rendered Claude CLI auth files
+type AuthFiles struct {
+	ClaudeConfig []byte // ~/.claude.json
+	Credentials  []byte // ~/.claude/.credentials.json
+}
+
+// RenderAuthFiles renders the Claude CLI auth files from the CLAUDE_*
+// environment variables without writing them anywhere
+func RenderAuthFiles() (*AuthFiles, error) {
+	values, err := templateValues()
+	if err != nil {
+		return nil, err
+	}
+
+	claudeConfig, err := renderTemplate(ClaudeConfigTemplate, values)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := renderTemplate(CredentialsTemplate, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthFiles{ClaudeConfig: claudeConfig, Credentials: credentials}, nil
+}
+
+// GenerateAuthFiles renders the Claude CLI auth files into destDir using the
+// CLAUDE_* environment variables. The resulting layout mirrors the home
+// directory of an authenticated Claude CLI user:
+//
+//	destDir/.claude.json
+//	destDir/.claude/.credentials.json
+func GenerateAuthFiles(destDir string) error {
+	files, err := RenderAuthFiles()
+	if err != nil {
+		return err
+	}
+	claudeConfig, credentials := files.ClaudeConfig, files.Credentials
+
+	credentialsDir := filepath.Join(destDir, ".claude")
+	if err := os.MkdirAll(credentialsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create auth directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(destDir, ".claude.json"), claudeConfig, 0600); err != nil {
+		return fmt.Errorf("failed to write .claude.json: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(credentialsDir, ".credentials.json"), credentials, 0600); err != nil {
+		return fmt.Errorf("failed to write .credentials.json: %w", err)
+	}
+
+	return nil
+}
+
+// templateValues builds the placeholder replacements from the environment
+func templateValues() (map[string]string, error) {
+	accessToken := os.Getenv("CLAUDE_ACCESS_TOKEN")
+	if accessToken == "" {
+		return nil, fmt.Errorf("CLAUDE_ACCESS_TOKEN not set")
+	}
+
+	expiresAt := os.Getenv("CLAUDE_TOKEN_EXPIRES_AT")
+	if expiresAt == "" {
+		return nil, fmt.Errorf("CLAUDE_TOKEN_EXPIRES_AT not set")
+	}
+	// expiresAt is rendered as a bare JSON number, so it must be numeric
+	if _, err := strconv.ParseInt(expiresAt, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid CLAUDE_TOKEN_EXPIRES_AT %q: %w", expiresAt, err)
+	}
+
+	firstStartTime := os.Getenv("CLAUDE_FIRST_START_TIME")
+	if firstStartTime == "" {
+		firstStartTime = time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+
+	stringValues := map[string]string{
+		"TEMPLATE_FIRST_START_TIME": firstStartTime,
+		"TEMPLATE_USER_ID":          os.Getenv("CLAUDE_USER_ID"),
+		"TEMPLATE_ACCOUNT_UUID":     os.Getenv("CLAUDE_ACCOUNT_UUID"),
+		"TEMPLATE_EMAIL":            os.Getenv("CLAUDE_EMAIL"),
+		"TEMPLATE_ORG_UUID":         os.Getenv("CLAUDE_ORG_UUID"),
+		"TEMPLATE_ORG_ROLE":         os.Getenv("CLAUDE_ORG_ROLE"),
+		"TEMPLATE_ORG_NAME":         os.Getenv("CLAUDE_ORG_NAME"),
+		"TEMPLATE_MAX_TIER":         os.Getenv("CLAUDE_MAX_TIER"),
+		"TEMPLATE_ACCESS_TOKEN":     accessToken,
+	}
+
+	values := make(map[string]string, len(stringValues)+1)
+	for placeholder, value := range stringValues {
+		values[placeholder] = jsonEscape(value)
+	}
+	values["TEMPLATE_EXPIRES_AT"] = expiresAt
+
+	return values, nil
+}
+
+// renderTemplate reads a template and replaces all placeholders, verifying
+// that the result is still valid JSON
+func renderTemplate(name string, values map[string]string) ([]byte, error) {
+	path, err := findTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+
+	rendered := string(data)
+	for placeholder, value := range values {
+		rendered = strings.ReplaceAll(rendered, placeholder, value)
+	}
+
+	if !json.Valid([]byte(rendered)) {
+		return nil, fmt.Errorf("rendered %s is not valid JSON", name)
+	}
+
+	return []byte(rendered), nil
+}
+
+// findTemplate locates a template file, honouring CLAUDE_TEMPLATE_DIR first
+func findTemplate(name string) (string, error) {
+	dirs := templateSearchDirs
+	if dir := os.Getenv("CLAUDE_TEMPLATE_DIR"); dir != "" {
+		dirs = append([]string{dir}, dirs...)
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("template %s not found in %s", name, strings.Join(dirs, ", "))
+}
+
+// jsonEscape escapes a value for use inside an existing JSON string literal
+func jsonEscape(value string) string {
+	encoded, _ := json.Marshal(value)
+	return string(encoded[1 : len(encoded)-1])
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// repoTemplateDir holds the templates shipped at the repository root
+const repoTemplateDir = "../.."
+
+// setAuthEnv sets every CLAUDE_* variable, clearing those not in env
+func setAuthEnv(t *testing.T, env map[string]string) {
+	for _, name := range []string{
+		"CLAUDE_ACCESS_TOKEN", "CLAUDE_TOKEN_EXPIRES_AT", "CLAUDE_FIRST_START_TIME",
+		"CLAUDE_USER_ID", "CLAUDE_ACCOUNT_UUID", "CLAUDE_EMAIL", "CLAUDE_ORG_UUID",
+		"CLAUDE_ORG_ROLE", "CLAUDE_ORG_NAME", "CLAUDE_MAX_TIER",
+	} {
+		t.Setenv(name, env[name])
+	}
+}
+
+type renderedConfig struct {
+	FirstStartTime string `json:"firstStartTime"`
+	OauthAccount   struct {
+		EmailAddress     string `json:"emailAddress"`
+		OrganizationName string `json:"organizationName"`
+	} `json:"oauthAccount"`
+}
+
+type renderedCredentials struct {
+	ClaudeAiOauth struct {
+		AccessToken string `json:"accessToken"`
+		ExpiresAt   int64  `json:"expiresAt"`
+	} `json:"claudeAiOauth"`
+}
+
+// readJSON decodes a generated auth file
+func readJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s was not written: %v", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("%s is invalid JSON: %v", path, err)
+	}
+}
+
+func TestGenerateAuthFiles(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		wantErr        bool
+		wantToken      string
+		wantExpiresAt  int64
+		wantEmail      string
+		wantOrgName    string
+		wantFirstStart string // only checked when set
+	}{
+		{
+			name: "all values",
+			env: map[string]string{
+				"CLAUDE_ACCESS_TOKEN":     "sk-ant-oat01-token",
+				"CLAUDE_TOKEN_EXPIRES_AT": "1767225600000",
+				"CLAUDE_FIRST_START_TIME": "2025-01-01T00:00:00.000Z",
+				"CLAUDE_EMAIL":            "dev@example.com",
+				"CLAUDE_ORG_NAME":         "Example",
+			},
+			wantToken:      "sk-ant-oat01-token",
+			wantExpiresAt:  1767225600000,
+			wantEmail:      "dev@example.com",
+			wantOrgName:    "Example",
+			wantFirstStart: "2025-01-01T00:00:00.000Z",
+		},
+		{
+			name: "values are JSON-escaped",
+			env: map[string]string{
+				"CLAUDE_ACCESS_TOKEN":     `to"ken\`,
+				"CLAUDE_TOKEN_EXPIRES_AT": "1",
+				"CLAUDE_ORG_NAME":         "A \"quoted\"\norg",
+			},
+			wantToken:     `to"ken\`,
+			wantExpiresAt: 1,
+			wantOrgName:   "A \"quoted\"\norg",
+		},
+		{
+			name:    "missing access token",
+			env:     map[string]string{"CLAUDE_TOKEN_EXPIRES_AT": "1"},
+			wantErr: true,
+		},
+		{
+			name:    "missing expiry",
+			env:     map[string]string{"CLAUDE_ACCESS_TOKEN": "token"},
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric expiry",
+			env:     map[string]string{"CLAUDE_ACCESS_TOKEN": "token", "CLAUDE_TOKEN_EXPIRES_AT": "1, \"x\": 2"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CLAUDE_TEMPLATE_DIR", repoTemplateDir)
+			setAuthEnv(t, tt.env)
+
+			dest := t.TempDir()
+			err := GenerateAuthFiles(dest)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("GenerateAuthFiles succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateAuthFiles failed: %v", err)
+			}
+
+			var config renderedConfig
+			readJSON(t, filepath.Join(dest, ".claude.json"), &config)
+			var credentials renderedCredentials
+			readJSON(t, filepath.Join(dest, ".claude", ".credentials.json"), &credentials)
+
+			if got := credentials.ClaudeAiOauth.AccessToken; got != tt.wantToken {
+				t.Errorf("accessToken = %q, want %q", got, tt.wantToken)
+			}
+			if got := credentials.ClaudeAiOauth.ExpiresAt; got != tt.wantExpiresAt {
+				t.Errorf("expiresAt = %d, want %d", got, tt.wantExpiresAt)
+			}
+			if got := config.OauthAccount.EmailAddress; got != tt.wantEmail {
+				t.Errorf("emailAddress = %q, want %q", got, tt.wantEmail)
+			}
+			if got := config.OauthAccount.OrganizationName; got != tt.wantOrgName {
+				t.Errorf("organizationName = %q, want %q", got, tt.wantOrgName)
+			}
+			if tt.wantFirstStart != "" && config.FirstStartTime != tt.wantFirstStart {
+				t.Errorf("firstStartTime = %q, want %q", config.FirstStartTime, tt.wantFirstStart)
+			}
+			if config.FirstStartTime == "" {
+				t.Error("firstStartTime is empty")
+			}
+		})
+	}
+}
+
+func TestGenerateAuthFilesRejectsInvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		ClaudeConfigTemplate: `{"userID": TEMPLATE_USER_ID}`,
+		CredentialsTemplate:  `{"accessToken": "TEMPLATE_ACCESS_TOKEN"}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("CLAUDE_TEMPLATE_DIR", dir)
+	setAuthEnv(t, map[string]string{"CLAUDE_ACCESS_TOKEN": "token", "CLAUDE_TOKEN_EXPIRES_AT": "1", "CLAUDE_USER_ID": "user"})
+
+	if err := GenerateAuthFiles(t.TempDir()); err == nil {
+		t.Error("GenerateAuthFiles with an unquoted string placeholder succeeded, want error")
+	}
+}
+
+func TestGenerateAuthFilesPermissions(t *testing.T) {
+	t.Setenv("CLAUDE_TEMPLATE_DIR", repoTemplateDir)
+	setAuthEnv(t, map[string]string{"CLAUDE_ACCESS_TOKEN": "token", "CLAUDE_TOKEN_EXPIRES_AT": "1"})
+
+	dest := t.TempDir()
+	if err := GenerateAuthFiles(dest); err != nil {
+		t.Fatalf("GenerateAuthFiles failed: %v", err)
+	}
+
+	for _, path := range []string{".claude.json", filepath.Join(".claude", ".credentials.json")} {
+		info, err := os.Stat(filepath.Join(dest, path))
+		if err != nil {
+			t.Errorf("%s was not written: %v", path, err)
+			continue
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("%s has mode %v, want 0600", path, mode)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Alert thresholds before token expiry (see docs/TOKEN-RENEWAL.md)
+var alertThresholds = []time.Duration{
+	1 * time.Hour,
+	4 * time.Hour,
+	24 * time.Hour,
+}
+
+// GetTokenExpiry returns the expiry time of the Claude CLI access token
+// from CLAUDE_TOKEN_EXPIRES_AT (Unix milliseconds)
+func GetTokenExpiry() (time.Time, error) {
+	value := os.Getenv("CLAUDE_TOKEN_EXPIRES_AT")
+	if value == "" {
+		return time.Time{}, fmt.Errorf("CLAUDE_TOKEN_EXPIRES_AT not set")
+	}
+
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid CLAUDE_TOKEN_EXPIRES_AT %q: %w", value, err)
+	}
+
+	return time.UnixMilli(millis), nil
+}
+
+// GetTokenStatus returns a human readable description of the token status
+func GetTokenStatus() (string, error) {
+	expiresAt, err := GetTokenExpiry()
+	if err != nil {
+		return "", err
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return fmt.Sprintf("EXPIRED (expired %s ago at %s)",
+			(-remaining).Round(time.Minute), expiresAt.Format(time.RFC3339)), nil
+	}
+
+	return fmt.Sprintf("valid (expires in %s at %s)",
+		remaining.Round(time.Minute), expiresAt.Format(time.RFC3339)), nil
+}
+
+// GetTokenExpiryAlert returns an alert message when the token has expired or
+// is about to expire, or an empty string when no action is needed
+func GetTokenExpiryAlert() (string, error) {
+	expiresAt, err := GetTokenExpiry()
+	if err != nil {
+		return "", err
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return expiredAlert(expiresAt), nil
+	}
+
+	if threshold := alertThreshold(remaining); threshold > 0 {
+		return fmt.Sprintf("⚠️ **Claude CLI Token expires in %s** (at %s, %s alert)\n\nRun `make token-renewal` and update `.env-secret` before the token expires.",
+			remaining.Round(time.Minute), expiresAt.Format(time.RFC3339), formatThreshold(threshold)), nil
+	}
+
+	return "", nil
+}
+
+// expiredAlert builds the alert posted once the token has expired
+func expiredAlert(expiresAt time.Time) string {
+	return fmt.Sprintf(`🚨 **URGENT: Claude CLI Token EXPIRED!**
+
+The Claude CLI authentication token has expired (at %s). Container orchestration will fail until renewed.
+
+**Required Action:**
+1. Run `+"`claude login`"+` on the host system
+2. Follow the browser authentication flow
+3. Update `+"`.env-secret`"+` with new token values
+4. Restart container orchestration system`, expiresAt.Format(time.RFC3339))
+}
+
+// alertThreshold returns the smallest threshold the remaining time falls
+// under, or 0 when the token is not close to expiry
+func alertThreshold(remaining time.Duration) time.Duration {
+	for _, threshold := range alertThresholds {
+		if remaining <= threshold {
+			return threshold
+		}
+	}
+	return 0
+}
+
+func formatThreshold(threshold time.Duration) string {
+	return fmt.Sprintf("%dh", int(threshold.Hours()))
+}
+
+// TokenMonitor periodically checks the token expiry and reports alerts
+type TokenMonitor struct {
+	callback      func(message string) error
+	checkInterval time.Duration
+	lastLevel     time.Duration
+}
+
+// NewTokenMonitor creates a token monitor that calls callback for every new alert
+func NewTokenMonitor(callback func(message string) error) *TokenMonitor {
+	return &TokenMonitor{
+		callback:      callback,
+		checkInterval: 30 * time.Minute,
+	}
+}
+
+// SetCheckInterval overrides the default check interval
+func (tm *TokenMonitor) SetCheckInterval(interval time.Duration) {
+	tm.checkInterval = interval
+}
+
+// Start runs the monitor until the context is cancelled
+func (tm *TokenMonitor) Start(ctx context.Context) error {
+	log.Printf("Starting token monitor (interval: %v)", tm.checkInterval)
+
+	tm.check()
+
+	ticker := time.NewTicker(tm.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			tm.check()
+		}
+	}
+}
+
+// check evaluates the token and invokes the callback once per alert level,
+// so a 24h warning is not repeated on every tick
+func (tm *TokenMonitor) check() {
+	expiresAt, err := GetTokenExpiry()
+	if err != nil {
+		log.Printf("Token monitor: %v", err)
+		return
+	}
+
+	remaining := time.Until(expiresAt)
+	level := alertThreshold(remaining)
+	if remaining <= 0 {
+		// Use a negative level so expiry is reported even after the 1h alert
+		level = -1
+	}
+
+	if level == 0 {
+		tm.lastLevel = 0
+		return
+	}
+	if level == tm.lastLevel {
+		return
+	}
+	tm.lastLevel = level
+
+	message, err := GetTokenExpiryAlert()
+	if err != nil || message == "" {
+		return
+	}
+
+	if err := tm.callback(message); err != nil {
+		log.Printf("Token monitor: alert callback failed: %v", err)
+	}
+}
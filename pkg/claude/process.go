@@ -0,0 +1,17 @@
+package claude
+
+import "fmt"
+
+// PIDFile is where a worker records the pid of its running Claude CLI
+const PIDFile = "/tmp/claude-cli.pid"
+
+// StopCommand asks the Claude CLI started with Exec to exit. Closing an exec
+// stream does not end the process in a pod or container, so a cancelled task
+// runs this in the worker before it is removed.
+const StopCommand = "[ -f " + PIDFile + " ] && kill -TERM \"$(cat " + PIDFile + ")\" 2>/dev/null; rm -f " + PIDFile
+
+// Exec returns a shell command that records its pid in PIDFile and then
+// replaces the shell with command, so the pid is the Claude CLI's own
+func Exec(command string) string {
+	return fmt.Sprintf("echo $$ > %s && exec %s", PIDFile, command)
+}
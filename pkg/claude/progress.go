@@ -0,0 +1,220 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecentTools is how many tool calls the status comment lists
+const maxRecentTools = 5
+
+// Todo is an item of Claude's TodoWrite list
+type Todo struct {
+	Content string `json:"content"`
+	Status  string `json:"status"` // pending, in_progress, completed
+}
+
+// Progress accumulates stream events into a live view of a running task
+type Progress struct {
+	header    string
+	startedAt time.Time
+
+	mu          sync.Mutex
+	sessionID   string
+	todos       []Todo
+	recentTools []string
+	toolCalls   int
+	lastText    string
+	result      *StreamEvent
+}
+
+// NewProgress creates a progress tracker; header is shown above the status
+func NewProgress(header string) *Progress {
+	return &Progress{header: header, startedAt: time.Now()}
+}
+
+// Apply updates the progress with a stream event
+func (p *Progress) Apply(event *StreamEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if event.SessionID != "" {
+		p.sessionID = event.SessionID
+	}
+
+	switch event.Type {
+	case EventAssistant:
+		if event.Message == nil {
+			return
+		}
+		for _, block := range event.Message.Content {
+			switch block.Type {
+			case "text":
+				if text := strings.TrimSpace(block.Text); text != "" {
+					p.lastText = text
+				}
+			case "tool_use":
+				p.toolCalls++
+				if block.Name == "TodoWrite" {
+					var input struct {
+						Todos []Todo `json:"todos"`
+					}
+					if err := json.Unmarshal(block.Input, &input); err == nil {
+						p.todos = input.Todos
+					}
+					continue
+				}
+				p.recentTools = append(p.recentTools, describeTool(block))
+				if len(p.recentTools) > maxRecentTools {
+					p.recentTools = p.recentTools[len(p.recentTools)-maxRecentTools:]
+				}
+			}
+		}
+	case EventResult:
+		p.result = event
+	}
+}
+
+// Result returns the final result event, or nil while the task is running
+func (p *Progress) Result() *StreamEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.result
+}
+
+// SessionID returns the Claude session ID reported by the stream
+func (p *Progress) SessionID() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.sessionID
+}
+
+// Markdown renders the progress as a GitHub comment
+func (p *Progress) Markdown() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b strings.Builder
+	if p.header != "" {
+		b.WriteString(p.header + "\n\n")
+	}
+
+	elapsed := time.Since(p.startedAt).Round(time.Second)
+	switch {
+	case p.result == nil:
+		fmt.Fprintf(&b, "🔄 **Claudeが作業中です** (経過時間: %s)\n", elapsed)
+	case p.result.IsError:
+		fmt.Fprintf(&b, "❌ **エラーで終了しました** (経過時間: %s)\n", elapsed)
+	default:
+		fmt.Fprintf(&b, "✅ **作業が完了しました** (経過時間: %s)\n", elapsed)
+	}
+	p.writeActivity(&b)
+	return b.String()
+}
+
+// writeActivity renders the TODO list, recent tool calls and latest message;
+// p.mu must be held
+func (p *Progress) writeActivity(b *strings.Builder) {
+	if len(p.todos) > 0 {
+		b.WriteString("\n**TODO:**\n")
+		for _, todo := range p.todos {
+			switch todo.Status {
+			case "completed":
+				fmt.Fprintf(b, "- [x] %s\n", todo.Content)
+			case "in_progress":
+				fmt.Fprintf(b, "- [ ] 🔄 **%s**\n", todo.Content)
+			default:
+				fmt.Fprintf(b, "- [ ] %s\n", todo.Content)
+			}
+		}
+	}
+
+	if len(p.recentTools) > 0 {
+		fmt.Fprintf(b, "\n**最近の操作** (ツール呼び出し %d 回):\n", p.toolCalls)
+		for _, tool := range p.recentTools {
+			fmt.Fprintf(b, "- %s\n", tool)
+		}
+	}
+
+	if p.lastText != "" && p.result == nil {
+		b.WriteString("\n**最新のメッセージ:**\n")
+		for _, line := range strings.Split(truncate(p.lastText, 500), "\n") {
+			b.WriteString("> " + line + "\n")
+		}
+	}
+}
+
+// Report calls update with the rendered progress every interval until ctx
+// is cancelled
+func (p *Progress) Report(ctx context.Context, interval time.Duration, update func(body string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if body := p.Markdown(); body != last {
+				update(body)
+				last = body
+			}
+		}
+	}
+}
+
+// RunStream runs a command producing stream-json on the writer passed to
+// run, feeding events to progress as they arrive. It returns the raw output.
+func RunStream(progress *Progress, run func(stdout io.Writer) error) (string, error) {
+	var raw bytes.Buffer
+	reader, writer := io.Pipe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tee := io.TeeReader(reader, &raw)
+		if err := ReadStream(tee, progress.Apply); err != nil {
+			// Keep draining so the command is never blocked on a full pipe
+			io.Copy(io.Discard, tee)
+		}
+	}()
+
+	err := run(writer)
+	writer.CloseWithError(err)
+	<-done
+
+	return raw.String(), err
+}
+
+// describeTool summarizes a tool call for the status comment
+func describeTool(block ContentBlock) string {
+	var input map[string]interface{}
+	json.Unmarshal(block.Input, &input)
+
+	for _, key := range []string{"command", "file_path", "pattern", "path", "url", "description"} {
+		if value, ok := input[key].(string); ok && value != "" {
+			value = strings.ReplaceAll(truncate(value, 80), "`", "'")
+			value = strings.ReplaceAll(value, "\n", " ")
+			return fmt.Sprintf("`%s`: `%s`", block.Name, value)
+		}
+	}
+	return fmt.Sprintf("`%s`", block.Name)
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,158 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxCommentMessage keeps the summary below GitHub's 65536 character limit
+const maxCommentMessage = 60000
+
+// Result subtypes reported by the Claude CLI
+const (
+	SubtypeSuccess              = "success"
+	SubtypeErrorMaxTurns        = "error_max_turns"
+	SubtypeErrorDuringExecution = "error_during_execution"
+)
+
+// TaskResult is the outcome of a Claude CLI run
+type TaskResult struct {
+	Subtype   string
+	IsError   bool
+	Message   string // final assistant message
+	NumTurns  int
+	Duration  time.Duration
+	CostUSD   float64
+	SessionID string
+
+	Transcript   string // raw CLI output
+	ArtifactPath string // where the transcript was saved, if it was
+}
+
+// NewTaskResult builds a result from the final "result" event of a run
+func NewTaskResult(event *StreamEvent, transcript string) *TaskResult {
+	return &TaskResult{
+		Subtype:    event.Subtype,
+		IsError:    event.IsError || (event.Subtype != "" && event.Subtype != SubtypeSuccess),
+		Message:    event.Result,
+		NumTurns:   event.NumTurns,
+		Duration:   time.Duration(event.DurationMS) * time.Millisecond,
+		CostUSD:    event.TotalCostUSD,
+		SessionID:  event.SessionID,
+		Transcript: transcript,
+	}
+}
+
+// ParseResult extracts the result from Claude CLI output produced with
+// --output-format json (a single object or an array of events) or
+// stream-json (one event per line)
+func ParseResult(output string) (*TaskResult, error) {
+	trimmed := strings.TrimSpace(output)
+
+	var event StreamEvent
+	if err := json.Unmarshal([]byte(trimmed), &event); err == nil && event.Type == EventResult {
+		return NewTaskResult(&event, output), nil
+	}
+
+	var events []StreamEvent
+	if err := json.Unmarshal([]byte(trimmed), &events); err == nil {
+		for i := len(events) - 1; i >= 0; i-- {
+			if events[i].Type == EventResult {
+				return NewTaskResult(&events[i], output), nil
+			}
+		}
+	}
+
+	var last *StreamEvent
+	ReadStream(strings.NewReader(output), func(e *StreamEvent) {
+		if e.Type == EventResult {
+			last = e
+		}
+	})
+	if last != nil {
+		return NewTaskResult(last, output), nil
+	}
+
+	return nil, fmt.Errorf("no result event found in Claude CLI output")
+}
+
+// Markdown renders the result as a GitHub comment
+func (r *TaskResult) Markdown() string {
+	var b strings.Builder
+
+	switch {
+	case r.Subtype == SubtypeErrorMaxTurns:
+		b.WriteString("⚠️ **最大ターン数に達したため処理を中断しました**\n\n")
+		b.WriteString("`@claude continue <追加指示>` で続きを依頼するか、`--max-turns` を増やして `@claude retry` してください。\n\n")
+	case r.IsError:
+		b.WriteString("❌ **タスクの実行中にエラーが発生しました**\n\n")
+	default:
+		b.WriteString("✅ **タスクが完了しました**\n\n")
+	}
+
+	if message := strings.TrimSpace(r.Message); message != "" {
+		b.WriteString(truncate(message, maxCommentMessage) + "\n\n")
+	}
+
+	b.WriteString("<details>\n<summary>実行の詳細</summary>\n\n")
+	b.WriteString("| 項目 | 値 |\n|---|---|\n")
+	if r.Subtype != "" {
+		fmt.Fprintf(&b, "| 結果 | `%s` |\n", r.Subtype)
+	}
+	fmt.Fprintf(&b, "| ターン数 | %d |\n", r.NumTurns)
+	if r.Duration > 0 {
+		fmt.Fprintf(&b, "| 所要時間 | %s |\n", r.Duration.Round(time.Second))
+	}
+	if r.CostUSD > 0 {
+		fmt.Fprintf(&b, "| コスト | $%.4f |\n", r.CostUSD)
+	}
+	if r.SessionID != "" {
+		fmt.Fprintf(&b, "| セッションID | `%s` |\n", r.SessionID)
+	}
+	if r.ArtifactPath != "" {
+		fmt.Fprintf(&b, "| トランスクリプト | `%s` |\n", r.ArtifactPath)
+	}
+	b.WriteString("\n</details>")
+
+	return b.String()
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// SaveTranscript writes the raw transcript to dir and records its path
+func (r *TaskResult) SaveTranscript(dir, repository string, issueNumber int) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-issue-%d-%s.jsonl",
+		unsafeNameChars.ReplaceAllString(repository, "_"), issueNumber, time.Now().UTC().Format("20060102T150405Z"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(r.Transcript), 0600); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+
+	r.ArtifactPath = path
+	return nil
+}
+
+// ResultFromStream builds the result of a stream-json run. Output without a
+// result event is kept as the message so nothing is lost.
+func ResultFromStream(progress *Progress, output string) *TaskResult {
+	if event := progress.Result(); event != nil {
+		return NewTaskResult(event, output)
+	}
+	if result, err := ParseResult(output); err == nil {
+		return result
+	}
+	return &TaskResult{
+		Message:    output,
+		SessionID:  progress.SessionID(),
+		Transcript: output,
+	}
+}
@@ -0,0 +1,117 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// SessionRecord describes the last Claude session of an issue
+type SessionRecord struct {
+	SessionID   string    `json:"session_id"`
+	Repository  string    `json:"repository"`
+	IssueNumber int       `json:"issue_number"`
+	Workspace   string    `json:"workspace"` // working directory the session ran in
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SessionStore keeps Claude session transcripts outside the workers, so a
+// later run on the same issue can resume the conversation
+type SessionStore struct {
+	dir string
+}
+
+// NewSessionStore creates a store in dir, typically on a persistent volume
+func NewSessionStore(dir string) (*SessionStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	return &SessionStore{dir: dir}, nil
+}
+
+// Save stores the session transcript of an issue, replacing the previous one
+func (s *SessionStore) Save(record SessionRecord, transcript []byte) error {
+	record.UpdatedAt = time.Now()
+	meta, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session record: %w", err)
+	}
+
+	base := s.path(record.Repository, record.IssueNumber)
+	if err := writeFileAtomic(base+".jsonl", transcript); err != nil {
+		return fmt.Errorf("failed to write session transcript: %w", err)
+	}
+	if err := writeFileAtomic(base+".json", meta); err != nil {
+		return fmt.Errorf("failed to write session record: %w", err)
+	}
+	return nil
+}
+
+// Load returns the stored session of an issue; found is false when there is none
+func (s *SessionStore) Load(repository string, issueNumber int) (record *SessionRecord, transcript []byte, found bool, err error) {
+	base := s.path(repository, issueNumber)
+
+	meta, err := os.ReadFile(base + ".json")
+	if os.IsNotExist(err) {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to read session record: %w", err)
+	}
+
+	record = &SessionRecord{}
+	if err := json.Unmarshal(meta, record); err != nil {
+		return nil, nil, false, fmt.Errorf("failed to parse session record: %w", err)
+	}
+
+	transcript, err = os.ReadFile(base + ".jsonl")
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to read session transcript: %w", err)
+	}
+	return record, transcript, true, nil
+}
+
+func (s *SessionStore) path(repository string, issueNumber int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s-issue-%d", unsafeNameChars.ReplaceAllString(repository, "_"), issueNumber))
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+var (
+	projectDirChars = regexp.MustCompile(`[^a-zA-Z0-9]`)
+	sessionIDRegex  = regexp.MustCompile(`^[a-zA-Z0-9-]{8,64}$`)
+)
+
+// SessionFile returns the shell path of a session transcript in a worker.
+// The Claude CLI keeps transcripts per working directory under
+// $HOME/.claude/projects, with the directory encoded as its name.
+func SessionFile(workspace, sessionID string) string {
+	return `"$HOME/.claude/projects/` + projectDirChars.ReplaceAllString(workspace, "-") + "/" + sessionID + `.jsonl"`
+}
+
+// ReadSessionScript prints the transcript of a session from a worker
+func ReadSessionScript(sessionID string) string {
+	return `cat "$(find "$HOME/.claude/projects" -name '` + sessionID + `.jsonl' | head -n 1)"`
+}
+
+// RestoreSessionScript writes a transcript read from stdin into place so
+// `claude --resume <id>` finds it
+func RestoreSessionScript(workspace, sessionID string) string {
+	file := SessionFile(workspace, sessionID)
+	return `mkdir -p "$(dirname ` + file + `)" && cat > ` + file
+}
+
+// ValidSessionID reports whether id looks like a Claude session ID, so it is
+// safe to embed in the scripts above
+func ValidSessionID(id string) bool {
+	return sessionIDRegex.MatchString(id)
+}
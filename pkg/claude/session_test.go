@@ -0,0 +1,99 @@
+package claude
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionStore(t *testing.T) {
+	store, err := NewSessionStore(filepath.Join(t.TempDir(), "sessions"))
+	if err != nil {
+		t.Fatalf("NewSessionStore failed: %v", err)
+	}
+
+	if _, _, found, err := store.Load("org/app", 1); err != nil || found {
+		t.Fatalf("Load of an unknown issue = found %v, %v; want not found", found, err)
+	}
+
+	saves := []struct {
+		record     SessionRecord
+		transcript string
+	}{
+		{SessionRecord{SessionID: "session-1", Repository: "org/app", IssueNumber: 1, Workspace: "/workspace/app"}, "first\n"},
+		{SessionRecord{SessionID: "session-2", Repository: "org/app", IssueNumber: 2, Workspace: "/workspace/app"}, "second\n"},
+		{SessionRecord{SessionID: "session-3", Repository: "other/app", IssueNumber: 1, Workspace: "/workspace/other"}, "third\n"},
+		// A later session of an issue replaces the earlier one
+		{SessionRecord{SessionID: "session-4", Repository: "org/app", IssueNumber: 1, Workspace: "/workspace/app"}, "fourth\n"},
+	}
+	for _, save := range saves {
+		if err := store.Save(save.record, []byte(save.transcript)); err != nil {
+			t.Fatalf("Save(%+v) failed: %v", save.record, err)
+		}
+	}
+
+	tests := []struct {
+		repository     string
+		issueNumber    int
+		wantSessionID  string
+		wantTranscript string
+	}{
+		{"org/app", 1, "session-4", "fourth\n"},
+		{"org/app", 2, "session-2", "second\n"},
+		{"other/app", 1, "session-3", "third\n"},
+	}
+	for _, tt := range tests {
+		record, transcript, found, err := store.Load(tt.repository, tt.issueNumber)
+		if err != nil || !found {
+			t.Errorf("Load(%s#%d) = found %v, %v; want found", tt.repository, tt.issueNumber, found, err)
+			continue
+		}
+		if record.SessionID != tt.wantSessionID || record.Repository != tt.repository || record.IssueNumber != tt.issueNumber {
+			t.Errorf("Load(%s#%d) record = %+v, want session %s", tt.repository, tt.issueNumber, record, tt.wantSessionID)
+		}
+		if record.UpdatedAt.IsZero() {
+			t.Errorf("Load(%s#%d) record has no UpdatedAt", tt.repository, tt.issueNumber)
+		}
+		if string(transcript) != tt.wantTranscript {
+			t.Errorf("Load(%s#%d) transcript = %q, want %q", tt.repository, tt.issueNumber, transcript, tt.wantTranscript)
+		}
+	}
+}
+
+func TestSessionStoreLoadMissingTranscript(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSessionStore(dir)
+	if err != nil {
+		t.Fatalf("NewSessionStore failed: %v", err)
+	}
+	record := SessionRecord{SessionID: "session-1", Repository: "org/app", IssueNumber: 1}
+	if err := store.Save(record, []byte("transcript")); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := os.Remove(store.path("org/app", 1) + ".jsonl"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, found, err := store.Load("org/app", 1); err == nil || found {
+		t.Errorf("Load without a transcript = found %v, %v; want error", found, err)
+	}
+}
+
+func TestValidSessionID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"0d3c5f8e-1b2a-4c5d-9e8f-7a6b5c4d3e2f", true},
+		{"abcd1234", true},
+		{"short", false},
+		{"", false},
+		{"abc'; rm -rf /; echo '", false},
+		{"../../etc/passwd", false},
+	}
+	for _, tt := range tests {
+		if got := ValidSessionID(tt.id); got != tt.want {
+			t.Errorf("ValidSessionID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
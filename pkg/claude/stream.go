@@ -0,0 +1,98 @@
+package claude
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// maxStreamLine bounds a single stream-json line; tool results can be large
+const maxStreamLine = 16 * 1024 * 1024
+
+// Event types emitted by `claude --print --verbose --output-format stream-json`
+const (
+	EventSystem    = "system"
+	EventAssistant = "assistant"
+	EventUser      = "user"
+	EventResult    = "result"
+)
+
+// StreamEvent is one line of Claude CLI stream-json output
+type StreamEvent struct {
+	Type      string   `json:"type"`
+	Subtype   string   `json:"subtype,omitempty"`
+	SessionID string   `json:"session_id,omitempty"`
+	Message   *Message `json:"message,omitempty"`
+
+	// Set on the final "result" event
+	Result       string  `json:"result,omitempty"`
+	IsError      bool    `json:"is_error,omitempty"`
+	NumTurns     int     `json:"num_turns,omitempty"`
+	DurationMS   int64   `json:"duration_ms,omitempty"`
+	TotalCostUSD float64 `json:"total_cost_usd,omitempty"`
+}
+
+// Message is an assistant or user message carried by a stream event
+type Message struct {
+	Role    string         `json:"role,omitempty"`
+	Content []ContentBlock `json:"content"`
+}
+
+// ContentBlock is a text, tool_use or tool_result block of a message
+type ContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// UnmarshalJSON accepts message content given either as a string or as blocks
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.Role = raw.Role
+
+	var text string
+	if err := json.Unmarshal(raw.Content, &text); err == nil {
+		m.Content = []ContentBlock{{Type: "text", Text: text}}
+		return nil
+	}
+	return json.Unmarshal(raw.Content, &m.Content)
+}
+
+// ParseStreamEvent parses a single stream-json line
+func ParseStreamEvent(line []byte) (*StreamEvent, error) {
+	var event StreamEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ReadStream parses stream-json output as it arrives and calls handle for
+// every event. Lines that are not JSON (such as CLI warnings) are skipped.
+func ReadStream(r io.Reader, handle func(*StreamEvent)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		event, err := ParseStreamEvent([]byte(line))
+		if err != nil {
+			continue
+		}
+		handle(event)
+	}
+	return scanner.Err()
+}
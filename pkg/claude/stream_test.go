@@ -0,0 +1,108 @@
+package claude
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMessageUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Message
+		wantErr bool
+	}{
+		{
+			name: "string content",
+			data: `{"role":"user","content":"fix the bug"}`,
+			want: Message{Role: "user", Content: []ContentBlock{{Type: "text", Text: "fix the bug"}}},
+		},
+		{
+			name: "content blocks",
+			data: `{"role":"assistant","content":[{"type":"text","text":"Reading"},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"main.go"}}]}`,
+			want: Message{Role: "assistant", Content: []ContentBlock{
+				{Type: "text", Text: "Reading"},
+				{Type: "tool_use", ID: "t1", Name: "Read", Input: json.RawMessage(`{"file_path":"main.go"}`)},
+			}},
+		},
+		{
+			name: "empty content",
+			data: `{"role":"assistant","content":[]}`,
+			want: Message{Role: "assistant", Content: []ContentBlock{}},
+		},
+		{name: "content of the wrong type", data: `{"role":"user","content":42}`, wantErr: true},
+		{name: "not an object", data: `"hello"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Message
+			err := json.Unmarshal([]byte(tt.data), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %+v, want error", tt.data, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) failed: %v", tt.data, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal(%s) = %+v, want %+v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadStream(t *testing.T) {
+	output := strings.Join([]string{
+		`{"type":"system","subtype":"init","session_id":"abc-123"}`,
+		`Warning: not a JSON line`,
+		``,
+		`  {"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Done"}]}}  `,
+		`{"type":"user", broken`,
+		`{"type":"result","subtype":"success","result":"All tests pass","num_turns":3,"duration_ms":1500,"total_cost_usd":0.25}`,
+	}, "\n")
+
+	var events []*StreamEvent
+	if err := ReadStream(strings.NewReader(output), func(event *StreamEvent) {
+		events = append(events, event)
+	}); err != nil {
+		t.Fatalf("ReadStream failed: %v", err)
+	}
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	if want := []string{EventSystem, EventAssistant, EventResult}; !reflect.DeepEqual(types, want) {
+		t.Fatalf("ReadStream event types = %v, want %v", types, want)
+	}
+	if events[0].SessionID != "abc-123" {
+		t.Errorf("session_id = %q, want abc-123", events[0].SessionID)
+	}
+	if text := events[1].Message.Content[0].Text; text != "Done" {
+		t.Errorf("assistant text = %q, want Done", text)
+	}
+	result := events[2]
+	if result.Result != "All tests pass" || result.NumTurns != 3 || result.DurationMS != 1500 || result.TotalCostUSD != 0.25 {
+		t.Errorf("result event = %+v", result)
+	}
+}
+
+func TestReadStreamLongLine(t *testing.T) {
+	text := strings.Repeat("x", 1024*1024)
+	line := `{"type":"user","message":{"role":"user","content":"` + text + `"}}`
+
+	var got string
+	if err := ReadStream(strings.NewReader(line+"\n"), func(event *StreamEvent) {
+		got = event.Message.Content[0].Text
+	}); err != nil {
+		t.Fatalf("ReadStream failed: %v", err)
+	}
+	if len(got) != len(text) {
+		t.Errorf("got %d bytes of text, want %d", len(got), len(text))
+	}
+}
@@ -0,0 +1,61 @@
+package claude
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/claude-automation/pkg/config"
+)
+
+// maxPartialOutputLines is how much command output PartialMarkdown quotes
+const maxPartialOutputLines = 20
+
+var phaseNames = map[config.Phase]string{
+	config.PhaseTask:   "タスク全体",
+	config.PhaseClone:  "リポジトリのクローン",
+	config.PhaseClaude: "Claudeの実行",
+	config.PhasePush:   "変更のPush",
+}
+
+// TimeoutMarkdown renders the comment for a task stopped by a timeout with
+// the PartialMarkdown of what ran before it
+func TimeoutMarkdown(timeout *config.TimeoutError, progress *Progress, result *TaskResult, output string) string {
+	var b strings.Builder
+	name := phaseNames[timeout.Phase]
+	if name == "" {
+		name = string(timeout.Phase)
+	}
+	fmt.Fprintf(&b, "⏱️ **タイムアウトしました**: %s (`%s`) が上限の %v を超えたため停止しました。\n", name, timeout.Phase, timeout.Limit)
+
+	b.WriteString(PartialMarkdown(progress, result, output))
+
+	b.WriteString("\n上限は repo-mapping.yaml の `timeouts` または `resource_limits.timeout` で変更できます。")
+	if progress != nil && progress.SessionID() != "" {
+		b.WriteString("`@claude continue <follow-up>` で中断したセッションから再開できます。")
+	}
+	return b.String()
+}
+
+// PartialMarkdown renders what a stopped task left behind: Claude's progress,
+// where the partial transcript in result was saved and the tail of the
+// command output of a clone or push. progress and result may be nil.
+func PartialMarkdown(progress *Progress, result *TaskResult, output string) string {
+	var b strings.Builder
+	if progress != nil {
+		progress.mu.Lock()
+		progress.writeActivity(&b)
+		progress.mu.Unlock()
+	}
+
+	if result != nil && result.ArtifactPath != "" {
+		fmt.Fprintf(&b, "\n**途中までのトランスクリプト:** `%s`\n", result.ArtifactPath)
+	}
+	if output = strings.TrimSpace(output); output != "" {
+		lines := strings.Split(output, "\n")
+		if len(lines) > maxPartialOutputLines {
+			lines = lines[len(lines)-maxPartialOutputLines:]
+		}
+		fmt.Fprintf(&b, "\n**途中までの出力:**\n```\n%s\n```\n", strings.Join(lines, "\n"))
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Check reports the values a worker could not use: malformed ports, env
+// entries, workspaces, users and timeouts. Quantities are checked by the
+// Kubernetes package, which owns their format.
+func (m *RepoMapping) Check() []error {
+	var errs []error
+	for _, entry := range m.entries() {
+		for _, err := range entry.Config.check() {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Source, err))
+		}
+	}
+
+	if m.Default == nil || m.Default.Image == "" {
+		for _, name := range m.RepositoryNames() {
+			if isPattern(name) {
+				continue
+			}
+			if resolved := m.Lookup(name); resolved == nil || resolved.Image == "" {
+				errs = append(errs, fmt.Errorf("repositories.%s: no image, and the default entry has none", name))
+			}
+		}
+	}
+
+	if m.ResourceLimits != nil && m.ResourceLimits.Timeout != "" {
+		if timeout, err := time.ParseDuration(m.ResourceLimits.Timeout); err != nil || timeout <= 0 {
+			errs = append(errs, fmt.Errorf("resource_limits: invalid timeout %q (expected a duration such as 30m or 1h)", m.ResourceLimits.Timeout))
+		}
+	}
+	if _, _, err := m.Security.UserGroup(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// entries returns every configuration entry with its source, in file order
+// where the file defines one
+func (m *RepoMapping) entries() []Layer {
+	var entries []Layer
+	if m.Default != nil {
+		entries = append(entries, Layer{Source: "default", Config: m.Default})
+	}
+	for _, name := range m.RepositoryNames() {
+		if config := m.Repositories[name]; config != nil {
+			entries = append(entries, Layer{Source: "repositories." + name, Config: config})
+		}
+	}
+	owners := make([]string, 0, len(m.Organizations))
+	for owner := range m.Organizations {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	for _, owner := range owners {
+		if config := m.Organizations[owner]; config != nil {
+			entries = append(entries, Layer{Source: "organizations." + owner, Config: config})
+		}
+	}
+	for i, rule := range m.Rules {
+		entries = append(entries, Layer{Source: rule.source(i), Config: &rule.Repository})
+	}
+	return entries
+}
+
+func (r *Repository) check() []error {
+	var errs []error
+	if r.Workspace != "" && !path.IsAbs(r.Workspace) {
+		errs = append(errs, fmt.Errorf("workspace %q must be an absolute path", r.Workspace))
+	}
+	for _, env := range r.Env {
+		name, _, ok := strings.Cut(env, "=")
+		if !ok || !envNameRegex.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid env %q (expected NAME=value)", env))
+		}
+	}
+	for _, spec := range r.Ports {
+		if _, err := ParsePort(spec); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, r.Timeouts.check()...)
+	return errs
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Target is what a worker configuration is selected for
+type Target struct {
+	Repository string   // owner/name
+	Labels     []string // labels of the issue
+	Language   string   // primary language reported by GitHub; may be empty
+}
+
+// Rule applies its configuration to tasks with any of its labels or
+// languages. A rule with both only applies when both match.
+type Rule struct {
+	Name       string   `yaml:"name"`
+	Labels     []string `yaml:"labels,omitempty"`
+	Languages  []string `yaml:"languages,omitempty"`
+	Repository `yaml:",inline"`
+}
+
+// Layer is one mapping entry that applies to a target
+type Layer struct {
+	Source string // e.g. "default", "organizations.worldscandy" or "rules[0] (python)"
+	Config *Repository
+}
+
+// Validate checks the repository patterns and rules
+func (m *RepoMapping) Validate() error {
+	for name := range m.Repositories {
+		owner, repo, ok := strings.Cut(name, "/")
+		if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return fmt.Errorf("invalid repositories key %q (expected owner/name or a pattern such as owner/*-service)", name)
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return fmt.Errorf("invalid repositories pattern %q: %w", name, err)
+		}
+	}
+	for owner := range m.Organizations {
+		if owner == "" || strings.Contains(owner, "/") {
+			return fmt.Errorf("invalid organizations key %q (expected an owner without /)", owner)
+		}
+	}
+	for i, rule := range m.Rules {
+		if rule == nil || (len(rule.Labels) == 0 && len(rule.Languages) == 0) {
+			return fmt.Errorf("rules[%d] needs labels or languages", i)
+		}
+	}
+	return nil
+}
+
+// UsesLanguages reports whether any rule depends on the repository language,
+// so callers only look it up when needed
+func (m *RepoMapping) UsesLanguages() bool {
+	if m == nil {
+		return false
+	}
+	for _, rule := range m.Rules {
+		if len(rule.Languages) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Layers returns the entries that apply to a target, from the broadest to
+// the most specific:
+//
+//  1. default
+//  2. organizations.<owner>
+//  3. rules selected by language only, in file order
+//  4. repositories patterns, the least specific first
+//  5. repositories.<owner/name>
+//  6. rules selected by labels, in file order
+//
+// Resolve merges them in this order, so a later entry only overrides the
+// fields it sets.
+func (m *RepoMapping) Layers(target Target) []Layer {
+	if m == nil {
+		return nil
+	}
+	var layers []Layer
+	add := func(source string, config *Repository) {
+		if config != nil {
+			layers = append(layers, Layer{Source: source, Config: config})
+		}
+	}
+
+	add("default", m.Default)
+
+	owner, _, _ := strings.Cut(target.Repository, "/")
+	for name, config := range m.Organizations {
+		if strings.EqualFold(name, owner) {
+			add("organizations."+name, config)
+		}
+	}
+
+	for i, rule := range m.Rules {
+		if len(rule.Labels) == 0 && matchesAny(rule.Languages, []string{target.Language}) {
+			add(rule.source(i), &rule.Repository)
+		}
+	}
+
+	var patterns []string
+	var exact string
+	for name := range m.Repositories {
+		switch {
+		case !isPattern(name):
+			if strings.EqualFold(name, target.Repository) {
+				exact = name
+			}
+		case matchPattern(name, target.Repository):
+			patterns = append(patterns, name)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if a, b := patternSpecificity(patterns[i]), patternSpecificity(patterns[j]); a != b {
+			return a < b
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, name := range patterns {
+		add("repositories."+name, m.Repositories[name])
+	}
+	if exact != "" {
+		add("repositories."+exact, m.Repositories[exact])
+	}
+
+	for i, rule := range m.Rules {
+		if len(rule.Labels) == 0 || !matchesAny(rule.Labels, target.Labels) {
+			continue
+		}
+		if len(rule.Languages) > 0 && !matchesAny(rule.Languages, []string{target.Language}) {
+			continue
+		}
+		add(rule.source(i), &rule.Repository)
+	}
+	return layers
+}
+
+// Resolve merges the layers of a target into a new configuration. It
+// returns nil when no entry applies.
+func (m *RepoMapping) Resolve(target Target) *Repository {
+	var resolved *Repository
+	for _, layer := range m.Layers(target) {
+		if resolved == nil {
+			resolved = layer.Config.Clone()
+			continue
+		}
+		resolved.Merge(layer.Config)
+	}
+	return resolved
+}
+
+// Merge overrides the fields set in over. Env is merged by variable name and
+// commands by name, where an empty command removes it; ports are replaced
+// and timeouts are overridden per phase.
+func (r *Repository) Merge(over *Repository) {
+	if over == nil {
+		return
+	}
+	if over.Image != "" {
+		r.Image = over.Image
+	}
+	if over.Workspace != "" {
+		r.Workspace = over.Workspace
+	}
+	for _, env := range over.Env {
+		name, _, _ := strings.Cut(env, "=")
+		replaced := false
+		for i, existing := range r.Env {
+			if existingName, _, _ := strings.Cut(existing, "="); existingName == name {
+				r.Env[i] = env
+				replaced = true
+			}
+		}
+		if !replaced {
+			r.Env = append(r.Env, env)
+		}
+	}
+	if len(over.Ports) > 0 {
+		r.Ports = append([]string(nil), over.Ports...)
+	}
+	for name, command := range over.Commands {
+		if r.Commands == nil {
+			r.Commands = make(map[string]string)
+		}
+		if strings.TrimSpace(command) == "" {
+			delete(r.Commands, name)
+			continue
+		}
+		r.Commands[name] = command
+	}
+	if over.Timeouts != nil {
+		if r.Timeouts == nil {
+			r.Timeouts = &Timeouts{}
+		}
+		r.Timeouts.merge(over.Timeouts)
+	}
+}
+
+func (r *Rule) source(index int) string {
+	if r.Name != "" {
+		return fmt.Sprintf("rules[%d] (%s)", index, r.Name)
+	}
+	return fmt.Sprintf("rules[%d]", index)
+}
+
+func isPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+func matchPattern(pattern, repository string) bool {
+	matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(repository))
+	return matched
+}
+
+// patternSpecificity counts the literal characters of a pattern, so
+// owner/*-service is applied after owner/*
+func patternSpecificity(pattern string) int {
+	n := 0
+	for _, c := range pattern {
+		if c != '*' && c != '?' {
+			n++
+		}
+	}
+	return n
+}
+
+// matchesAny reports whether any of values is in wanted, ignoring case
+func matchesAny(wanted, values []string) bool {
+	for _, w := range wanted {
+		for _, v := range values {
+			if v != "" && strings.EqualFold(w, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testMapping = `
+default:
+  image: base
+  workspace: /workspace
+  env: [A=1]
+  timeouts: {task: 1h}
+organizations:
+  acme:
+    image: acme
+    env: [B=2]
+rules:
+  - name: python
+    languages: [Python]
+    image: python
+  - name: gpu
+    labels: [gpu]
+    env: [GPU=1]
+  - name: gpu-python
+    labels: [gpu]
+    languages: [python]
+    commands: {test: pytest}
+repositories:
+  "acme/*":
+    env: [A=acme]
+  "acme/*-service":
+    ports: ["8080"]
+    commands: {lint: golangci-lint run}
+  acme/api-service:
+    image: api
+    commands: {lint: ""}
+    timeouts: {claude: 10m}
+`
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		repository string
+		want       bool
+	}{
+		{"acme/*", "acme/web", true},
+		{"acme/*", "ACME/Web", true},
+		{"ACME/*", "acme/web", true},
+		{"acme/*", "other/web", false},
+		{"acme/*", "acme/web/extra", false},
+		{"acme/*-service", "acme/api-service", true},
+		{"acme/*-service", "acme/api", false},
+		{"acme/?pi", "acme/api", true},
+		{"acme/[ab]*", "acme/billing", true},
+		{"acme/[ab]*", "acme/web", false},
+		{"*/app", "other/app", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.repository); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.repository, got, tt.want)
+		}
+	}
+}
+
+func TestLayers(t *testing.T) {
+	mapping, err := ParseRepoMapping([]byte(testMapping))
+	if err != nil {
+		t.Fatalf("ParseRepoMapping failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target Target
+		want   []string
+	}{
+		{
+			name:   "unmatched repository gets the default",
+			target: Target{Repository: "other/app"},
+			want:   []string{"default"},
+		},
+		{
+			name:   "organization and pattern ignore case",
+			target: Target{Repository: "Acme/Web"},
+			want:   []string{"default", "organizations.acme", "repositories.acme/*"},
+		},
+		{
+			name:   "broadest to most specific",
+			target: Target{Repository: "acme/api-service", Labels: []string{"GPU"}, Language: "Python"},
+			want: []string{
+				"default",
+				"organizations.acme",
+				"rules[0] (python)",
+				"repositories.acme/*",
+				"repositories.acme/*-service",
+				"repositories.acme/api-service",
+				"rules[1] (gpu)",
+				"rules[2] (gpu-python)",
+			},
+		},
+		{
+			name:   "rule with labels and languages needs both",
+			target: Target{Repository: "other/app", Labels: []string{"gpu"}, Language: "Go"},
+			want:   []string{"default", "rules[1] (gpu)"},
+		},
+		{
+			name:   "language rule without a language",
+			target: Target{Repository: "other/app", Labels: []string{"docs"}},
+			want:   []string{"default"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, layer := range mapping.Layers(tt.target) {
+				got = append(got, layer.Source)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Layers(%+v) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	mapping, err := ParseRepoMapping([]byte(testMapping))
+	if err != nil {
+		t.Fatalf("ParseRepoMapping failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		mapping *RepoMapping
+		target  Target
+		want    *Repository
+	}{
+		{
+			name:    "nil mapping",
+			mapping: nil,
+			target:  Target{Repository: "acme/web"},
+			want:    nil,
+		},
+		{
+			name:    "no entry applies",
+			mapping: &RepoMapping{},
+			target:  Target{Repository: "acme/web"},
+			want:    nil,
+		},
+		{
+			name:    "default only",
+			mapping: mapping,
+			target:  Target{Repository: "other/app"},
+			want:    &Repository{Image: "base", Workspace: "/workspace", Env: []string{"A=1"}, Timeouts: &Timeouts{Task: "1h"}},
+		},
+		{
+			name:    "organization and pattern",
+			mapping: mapping,
+			target:  Target{Repository: "acme/web"},
+			want:    &Repository{Image: "acme", Workspace: "/workspace", Env: []string{"A=acme", "B=2"}, Timeouts: &Timeouts{Task: "1h"}},
+		},
+		{
+			name:    "all layers",
+			mapping: mapping,
+			target:  Target{Repository: "acme/api-service", Labels: []string{"gpu"}, Language: "Python"},
+			want: &Repository{
+				Image:     "api",
+				Workspace: "/workspace",
+				Env:       []string{"A=acme", "B=2", "GPU=1"},
+				Ports:     []string{"8080"},
+				Commands:  map[string]string{"test": "pytest"},
+				Timeouts:  &Timeouts{Task: "1h", Claude: "10m"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.mapping.Resolve(tt.target)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%+v) = %+v, want %+v", tt.target, got, tt.want)
+			}
+		})
+	}
+
+	// Resolving must not change the mapping
+	mapping.Resolve(Target{Repository: "acme/api-service", Labels: []string{"gpu"}, Language: "Python"})
+	if env := mapping.Default.Env; !reflect.DeepEqual(env, []string{"A=1"}) {
+		t.Errorf("Resolve changed the default env to %v", env)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		base Repository
+		over *Repository
+		want Repository
+	}{
+		{
+			name: "nil over",
+			base: Repository{Image: "base", Env: []string{"A=1"}},
+			over: nil,
+			want: Repository{Image: "base", Env: []string{"A=1"}},
+		},
+		{
+			name: "empty fields are kept",
+			base: Repository{Image: "base", Workspace: "/w", Ports: []string{"80"}},
+			over: &Repository{},
+			want: Repository{Image: "base", Workspace: "/w", Ports: []string{"80"}},
+		},
+		{
+			name: "env is merged by name",
+			base: Repository{Env: []string{"A=1", "B=2"}},
+			over: &Repository{Env: []string{"B=3", "C=4"}},
+			want: Repository{Env: []string{"A=1", "B=3", "C=4"}},
+		},
+		{
+			name: "ports are replaced",
+			base: Repository{Ports: []string{"80", "443"}},
+			over: &Repository{Ports: []string{"8080"}},
+			want: Repository{Ports: []string{"8080"}},
+		},
+		{
+			name: "commands are merged and an empty command removes one",
+			base: Repository{Commands: map[string]string{"setup": "make", "lint": "golint"}},
+			over: &Repository{Commands: map[string]string{"lint": " ", "test": "go test ./..."}},
+			want: Repository{Commands: map[string]string{"setup": "make", "test": "go test ./..."}},
+		},
+		{
+			name: "commands without base commands",
+			over: &Repository{Commands: map[string]string{"test": "go test ./..."}},
+			want: Repository{Commands: map[string]string{"test": "go test ./..."}},
+		},
+		{
+			name: "timeouts are overridden per phase",
+			base: Repository{Timeouts: &Timeouts{Task: "1h", Clone: "5m"}},
+			over: &Repository{Timeouts: &Timeouts{Clone: "10m", Push: "2m"}},
+			want: Repository{Timeouts: &Timeouts{Task: "1h", Clone: "10m", Push: "2m"}},
+		},
+		{
+			name: "timeouts without base timeouts",
+			over: &Repository{Timeouts: &Timeouts{Claude: "20m"}},
+			want: Repository{Timeouts: &Timeouts{Claude: "20m"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.base
+			got.Merge(tt.over)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge(%+v) = %+v, want %+v", tt.over, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RepoMappingSource reads the current repo-mapping.yaml content
+type RepoMappingSource struct {
+	Name string // for logs, e.g. the file path or configmap/<name>
+	Read func(ctx context.Context) ([]byte, error)
+}
+
+// FileSource reads the mapping from a file. A mounted ConfigMap is updated
+// in place by the kubelet, so it is watched the same way.
+func FileSource(path string) RepoMappingSource {
+	return RepoMappingSource{
+		Name: path,
+		Read: func(ctx context.Context) ([]byte, error) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read repo mapping: %w", err)
+			}
+			return data, nil
+		},
+	}
+}
+
+// RepoMappingStore holds the current mapping and replaces it when its source
+// changes. A mapping is swapped in as a whole and only once it is valid, so
+// readers see either the old or the new file. Tasks keep the *RepoMapping
+// they read at their start.
+type RepoMappingStore struct {
+	source RepoMappingSource
+	check  func(*RepoMapping) []error // extra validation; optional
+
+	current atomic.Pointer[RepoMapping]
+
+	mu        sync.Mutex
+	last      []byte // content of the last applied or rejected version
+	listeners []func(*RepoMapping)
+}
+
+// NewRepoMappingStore loads the mapping from source. check adds validation
+// on top of parsing and Check, e.g. of resource quantities.
+func NewRepoMappingStore(ctx context.Context, source RepoMappingSource, check func(*RepoMapping) []error) (*RepoMappingStore, error) {
+	s := &RepoMappingStore{source: source, check: check}
+	if _, err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Current returns the mapping in effect; nil-safe so callers without a
+// store get no mapping
+func (s *RepoMappingStore) Current() *RepoMapping {
+	if s == nil {
+		return nil
+	}
+	return s.current.Load()
+}
+
+// Source returns the name of the source the mapping is read from
+func (s *RepoMappingStore) Source() string {
+	return s.source.Name
+}
+
+// OnChange registers a function called with every newly applied mapping
+func (s *RepoMappingStore) OnChange(listener func(*RepoMapping)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// Reload reads the source and applies it when it changed and is valid. An
+// invalid version is reported once and the previous mapping stays in effect.
+func (s *RepoMappingStore) Reload(ctx context.Context) (changed bool, err error) {
+	data, err := s.source.Read(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last != nil && bytes.Equal(data, s.last) {
+		return false, nil
+	}
+	s.last = data
+
+	mapping, err := ParseRepoMapping(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", s.source.Name, err)
+	}
+	problems := mapping.Check()
+	if s.check != nil {
+		problems = append(problems, s.check(mapping)...)
+	}
+	if len(problems) > 0 {
+		return false, fmt.Errorf("%s: %w", s.source.Name, errors.Join(problems...))
+	}
+
+	s.current.Store(mapping)
+	for _, listener := range s.listeners {
+		listener(mapping)
+	}
+	return true, nil
+}
+
+// Watch reloads the mapping every interval until ctx is done. onResult is
+// called after every reload that applied or rejected a new version.
+func (s *RepoMappingStore) Watch(ctx context.Context, interval time.Duration, onResult func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := s.Reload(ctx)
+			switch {
+			case err != nil:
+				log.Printf("Warning: keeping the previous repo mapping: %v", err)
+			case changed:
+				log.Printf("Reloaded repo mapping from %s", s.source.Name)
+			default:
+				continue
+			}
+			if onResult != nil {
+				onResult(err)
+			}
+		}
+	}
+}
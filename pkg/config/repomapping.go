@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultRepoMappingPath is used when REPO_MAPPING_FILE is not set
+const DefaultRepoMappingPath = "config/repo-mapping.yaml"
+
+// RepoMapping is the repo-mapping.yaml file shared by the Docker and
+// Kubernetes workers. See Layers for how entries are combined.
+type RepoMapping struct {
+	Repositories   map[string]*Repository `yaml:"repositories"`  // owner/name or a glob such as owner/*-service
+	Organizations  map[string]*Repository `yaml:"organizations"` // owner -> defaults for its repositories
+	Rules          []*Rule                `yaml:"rules"`
+	Default        *Repository            `yaml:"default"`
+	ResourceLimits *ResourceLimits        `yaml:"resource_limits"`
+	Security       *Security              `yaml:"security"`
+}
+
+// Repository is the worker configuration of a repository
+type Repository struct {
+	Image     string            `yaml:"image"`
+	Workspace string            `yaml:"workspace"`
+	Env       []string          `yaml:"env,omitempty"`   // NAME=value
+	Ports     []string          `yaml:"ports,omitempty"` // "8080", "3000:3000" or "53/udp"
+	Commands  map[string]string `yaml:"commands,omitempty"`
+	Timeouts  *Timeouts         `yaml:"timeouts,omitempty"`
+}
+
+// ResourceLimits constrains every worker. Sizes use Docker notation
+// ("512m", "1g"); Kubernetes quantities ("512Mi") are accepted as well.
+type ResourceLimits struct {
+	Memory  string `yaml:"memory"`
+	CPU     string `yaml:"cpu"`
+	Disk    string `yaml:"disk"`
+	Timeout string `yaml:"timeout"`
+}
+
+// Security is applied to every worker
+type Security struct {
+	ReadOnlyRoot    bool         `yaml:"read_only_root"`
+	NoNewPrivileges bool         `yaml:"no_new_privileges"`
+	Privileged      bool         `yaml:"privileged"`
+	User            string       `yaml:"user"` // uid or uid:gid
+	Capabilities    Capabilities `yaml:"capabilities"`
+}
+
+// Capabilities are Linux capabilities dropped from or added to workers
+type Capabilities struct {
+	Drop []string `yaml:"drop"`
+	Add  []string `yaml:"add"`
+}
+
+// Port is a parsed entry of Repository.Ports
+type Port struct {
+	HostPort      int32 // 0 when the port is not published on the host
+	ContainerPort int32
+	Protocol      string // tcp or udp
+}
+
+// RepoMappingPath returns REPO_MAPPING_FILE or the default path
+func RepoMappingPath() string {
+	if path := os.Getenv("REPO_MAPPING_FILE"); path != "" {
+		return path
+	}
+	return DefaultRepoMappingPath
+}
+
+// LoadRepoMapping reads and parses a repo-mapping.yaml file
+func LoadRepoMapping(path string) (*RepoMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repo mapping: %w", err)
+	}
+	mapping, err := ParseRepoMapping(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return mapping, nil
+}
+
+// ParseRepoMapping parses repo-mapping.yaml content. Unknown keys are errors
+// so typos do not silently fall back to defaults.
+func ParseRepoMapping(data []byte) (*RepoMapping, error) {
+	mapping := &RepoMapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse repo mapping: %w", err)
+	}
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// RepositoryNames returns the configured repository keys in sorted order
+func (m *RepoMapping) RepositoryNames() []string {
+	names := make([]string, 0, len(m.Repositories))
+	for name := range m.Repositories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the configuration of a repository without labels or
+// language. It returns nil when no entry applies.
+func (m *RepoMapping) Lookup(repository string) *Repository {
+	return m.Resolve(Target{Repository: repository})
+}
+
+// Clone returns a deep copy, so callers can add env without changing the mapping
+func (r *Repository) Clone() *Repository {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	clone.Env = append([]string(nil), r.Env...)
+	clone.Ports = append([]string(nil), r.Ports...)
+	if r.Commands != nil {
+		clone.Commands = make(map[string]string, len(r.Commands))
+		for name, command := range r.Commands {
+			clone.Commands[name] = command
+		}
+	}
+	if r.Timeouts != nil {
+		timeouts := *r.Timeouts
+		clone.Timeouts = &timeouts
+	}
+	return &clone
+}
+
+// SetupCommand returns the "setup" command run in the workspace after checkout
+func (r *Repository) SetupCommand() string {
+	if r == nil {
+		return ""
+	}
+	return strings.TrimSpace(r.Commands["setup"])
+}
+
+// CommandsHint lists the repository's commands other than setup for the
+// Claude prompt, or returns "" when there are none
+func (r *Repository) CommandsHint() string {
+	if r == nil {
+		return ""
+	}
+	var names []string
+	for name, command := range r.Commands {
+		if name != "setup" && strings.TrimSpace(command) != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Repository commands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "- %s: `%s`\n", name, r.Commands[name])
+	}
+	return b.String()
+}
+
+// ParsePort parses "8080", "3000:3000" or "53:53/udp"
+func ParsePort(spec string) (Port, error) {
+	port := Port{Protocol: "tcp"}
+	value := strings.TrimSpace(spec)
+	if p, protocol, ok := strings.Cut(value, "/"); ok {
+		protocol = strings.ToLower(protocol)
+		if protocol != "tcp" && protocol != "udp" {
+			return port, fmt.Errorf("invalid port %q: protocol must be tcp or udp", spec)
+		}
+		value, port.Protocol = p, protocol
+	}
+
+	host, container, published := strings.Cut(value, ":")
+	if !published {
+		container = host
+	}
+	containerPort, err := parsePortNumber(container)
+	if err != nil {
+		return port, fmt.Errorf("invalid port %q: %w", spec, err)
+	}
+	port.ContainerPort = containerPort
+	if published {
+		hostPort, err := parsePortNumber(host)
+		if err != nil {
+			return port, fmt.Errorf("invalid port %q: %w", spec, err)
+		}
+		port.HostPort = hostPort
+	}
+	return port, nil
+}
+
+func parsePortNumber(value string) (int32, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("%q is not a port number", value)
+	}
+	return int32(n), nil
+}
+
+// UserGroup parses User as uid or uid:gid; nil values mean not set
+func (s *Security) UserGroup() (uid, gid *int64, err error) {
+	if s == nil || s.User == "" {
+		return nil, nil, nil
+	}
+	user, group, hasGroup := strings.Cut(s.User, ":")
+	u, err := strconv.ParseInt(user, 10, 64)
+	if err != nil || u < 0 {
+		return nil, nil, fmt.Errorf("invalid security.user %q: expected uid or uid:gid", s.User)
+	}
+	uid = &u
+	if hasGroup {
+		g, err := strconv.ParseInt(group, 10, 64)
+		if err != nil || g < 0 {
+			return nil, nil, fmt.Errorf("invalid security.user %q: expected uid or uid:gid", s.User)
+		}
+		gid = &g
+	}
+	return uid, gid, nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Port
+		wantErr bool
+	}{
+		{spec: "8080", want: Port{ContainerPort: 8080, Protocol: "tcp"}},
+		{spec: " 8080 ", want: Port{ContainerPort: 8080, Protocol: "tcp"}},
+		{spec: "3000:3001", want: Port{HostPort: 3000, ContainerPort: 3001, Protocol: "tcp"}},
+		{spec: "53/udp", want: Port{ContainerPort: 53, Protocol: "udp"}},
+		{spec: "53:53/UDP", want: Port{HostPort: 53, ContainerPort: 53, Protocol: "udp"}},
+		{spec: "443/tcp", want: Port{ContainerPort: 443, Protocol: "tcp"}},
+		{spec: "65535", want: Port{ContainerPort: 65535, Protocol: "tcp"}},
+		{spec: "", wantErr: true},
+		{spec: "http", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "65536", wantErr: true},
+		{spec: "-1", wantErr: true},
+		{spec: "80/sctp", wantErr: true},
+		{spec: ":80", wantErr: true},
+		{spec: "80:", wantErr: true},
+		{spec: "1:2:3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePort(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePort(%q) = %+v, want error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParsePort(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePort(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Phase is a step of a task that can be bounded by its own timeout
+type Phase string
+
+const (
+	PhaseTask   Phase = "task" // the whole task, from worker creation to the final comment
+	PhaseClone  Phase = "clone"
+	PhaseClaude Phase = "claude"
+	PhasePush   Phase = "push"
+)
+
+// Timeouts are wall-clock limits such as "30m" for a repository's tasks. An
+// empty value leaves the phase unbounded; task defaults to
+// resource_limits.timeout.
+type Timeouts struct {
+	Task   string `yaml:"task,omitempty"`
+	Clone  string `yaml:"clone,omitempty"`
+	Claude string `yaml:"claude,omitempty"`
+	Push   string `yaml:"push,omitempty"`
+}
+
+// TaskTimeouts are the parsed limits of a task; phases without one are missing
+type TaskTimeouts map[Phase]time.Duration
+
+// TimeoutError is the cause of a context ended by a task or phase timeout
+type TimeoutError struct {
+	Phase Phase
+	Limit time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v", e.Phase, e.Limit)
+}
+
+// TaskTimeouts returns the limits for a task using the resolved repository
+// config r. Both m and r may be nil.
+func (m *RepoMapping) TaskTimeouts(r *Repository) TaskTimeouts {
+	timeouts := TaskTimeouts{}
+	if m != nil && m.ResourceLimits != nil {
+		timeouts.set(PhaseTask, m.ResourceLimits.Timeout)
+	}
+	if r != nil && r.Timeouts != nil {
+		timeouts.set(PhaseTask, r.Timeouts.Task)
+		timeouts.set(PhaseClone, r.Timeouts.Clone)
+		timeouts.set(PhaseClaude, r.Timeouts.Claude)
+		timeouts.set(PhasePush, r.Timeouts.Push)
+	}
+	return timeouts
+}
+
+// set records a limit; Check has already rejected malformed values
+func (t TaskTimeouts) set(phase Phase, value string) {
+	if value == "" {
+		return
+	}
+	if limit, err := time.ParseDuration(value); err == nil && limit > 0 {
+		t[phase] = limit
+	}
+}
+
+// WithTimeout returns a context that ends when the phase's limit is reached.
+// TimedOut reports the phase once it did.
+func (t TaskTimeouts) WithTimeout(ctx context.Context, phase Phase) (context.Context, context.CancelFunc) {
+	limit, ok := t[phase]
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, limit, &TimeoutError{Phase: phase, Limit: limit})
+}
+
+// TimedOut returns the timeout that ended ctx or one of its parents, or nil
+// when ctx is still running or was cancelled for another reason
+func TimedOut(ctx context.Context) *TimeoutError {
+	var timeout *TimeoutError
+	if errors.As(context.Cause(ctx), &timeout) {
+		return timeout
+	}
+	return nil
+}
+
+func (t *Timeouts) check() []error {
+	if t == nil {
+		return nil
+	}
+	var errs []error
+	for _, value := range []struct {
+		phase Phase
+		value string
+	}{
+		{PhaseTask, t.Task},
+		{PhaseClone, t.Clone},
+		{PhaseClaude, t.Claude},
+		{PhasePush, t.Push},
+	} {
+		if value.value == "" {
+			continue
+		}
+		if limit, err := time.ParseDuration(value.value); err != nil || limit <= 0 {
+			errs = append(errs, fmt.Errorf("timeouts: invalid %s timeout %q (expected a duration such as 30m or 1h)", value.phase, value.value))
+		}
+	}
+	return errs
+}
+
+// merge overrides the limits set in over
+func (t *Timeouts) merge(over *Timeouts) {
+	if over.Task != "" {
+		t.Task = over.Task
+	}
+	if over.Clone != "" {
+		t.Clone = over.Clone
+	}
+	if over.Claude != "" {
+		t.Claude = over.Claude
+	}
+	if over.Push != "" {
+		t.Push = over.Push
+	}
+}
@@ -1,18 +1,22 @@
 package container
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"gopkg.in/yaml.v2"
-	
 	"github.com/claude-automation/pkg/auth"
+	"github.com/claude-automation/pkg/config"
+	"github.com/claude-automation/pkg/workspace"
 )
 
 // ContainerManager manages worker containers for different repositories
@@ -20,47 +24,16 @@ type ContainerManager struct {
 	ConfigPath     string
 	WorkspacesDir  string
 	SessionsDir    string
-	RepoMapping    *RepoMappingConfig
+	RepoMapping    *RepoMappingConfig // use SetRepoMapping once containers are being created
+	mappingMu      sync.RWMutex
 	activeContainers map[string]*WorkerContainer
 }
 
-// RepoMappingConfig represents the repository mapping configuration
-type RepoMappingConfig struct {
-	Repositories map[string]*RepositoryConfig `yaml:"repositories"`
-	Default      *RepositoryConfig            `yaml:"default"`
-	ResourceLimits *ResourceLimits            `yaml:"resource_limits"`
-	Security     *SecurityConfig              `yaml:"security"`
-}
+// RepoMappingConfig is the repo-mapping.yaml file, shared with the Kubernetes workers
+type RepoMappingConfig = config.RepoMapping
 
-// RepositoryConfig defines configuration for a specific repository
-type RepositoryConfig struct {
-	Image     string            `yaml:"image"`
-	Workspace string            `yaml:"workspace"`
-	Env       []string          `yaml:"env,omitempty"`
-	Ports     []string          `yaml:"ports,omitempty"`
-	Commands  map[string]string `yaml:"commands,omitempty"`
-}
-
-// ResourceLimits defines container resource constraints
-type ResourceLimits struct {
-	Memory  string `yaml:"memory"`
-	CPU     string `yaml:"cpu"`
-	Disk    string `yaml:"disk"`
-	Timeout string `yaml:"timeout"`
-}
-
-// SecurityConfig defines container security settings
-type SecurityConfig struct {
-	ReadOnlyRoot      bool     `yaml:"read_only_root"`
-	NoNewPrivileges   bool     `yaml:"no_new_privileges"`
-	User              string   `yaml:"user"`
-	Capabilities      CapConfig `yaml:"capabilities"`
-}
-
-type CapConfig struct {
-	Drop []string `yaml:"drop"`
-	Add  []string `yaml:"add"`
-}
+// RepositoryConfig is the worker configuration of a repository
+type RepositoryConfig = config.Repository
 
 // WorkerContainer represents an active worker container
 type WorkerContainer struct {
@@ -92,22 +65,32 @@ func NewContainerManager(configPath, workspacesDir, sessionsDir string) (*Contai
 
 // loadConfig loads the repository mapping configuration
 func (cm *ContainerManager) loadConfig() error {
-	data, err := os.ReadFile(cm.ConfigPath)
+	mapping, err := config.LoadRepoMapping(cm.ConfigPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return err
 	}
+	cm.SetRepoMapping(mapping)
+	return nil
+}
 
-	cm.RepoMapping = &RepoMappingConfig{}
-	if err := yaml.Unmarshal(data, cm.RepoMapping); err != nil {
-		return fmt.Errorf("failed to parse config YAML: %w", err)
-	}
+// SetRepoMapping replaces the mapping used for containers created from now on
+func (cm *ContainerManager) SetRepoMapping(mapping *RepoMappingConfig) {
+	cm.mappingMu.Lock()
+	defer cm.mappingMu.Unlock()
+	cm.RepoMapping = mapping
+}
 
-	return nil
+func (cm *ContainerManager) currentMapping() *RepoMappingConfig {
+	cm.mappingMu.RLock()
+	defer cm.mappingMu.RUnlock()
+	return cm.RepoMapping
 }
 
-// CreateWorkerContainer creates a new worker container for the given issue
-func (cm *ContainerManager) CreateWorkerContainer(ctx context.Context, issueNumber int, repository string) (*WorkerContainer, error) {
-	containerID := fmt.Sprintf("claude-worker-%d-%s", issueNumber, strings.ReplaceAll(repository, "/", "-"))
+// CreateWorkerContainer creates a new worker container for the given issue.
+// repoConfig is usually resolved by the caller with labels and language; when
+// nil the repository's entry in the mapping is used.
+func (cm *ContainerManager) CreateWorkerContainer(ctx context.Context, issueNumber int, repository string, repoConfig *RepositoryConfig) (*WorkerContainer, error) {
+	containerID := workerContainerName(issueNumber, repository)
 	
 	// Check if container already exists
 	if existing, exists := cm.activeContainers[containerID]; exists {
@@ -116,7 +99,16 @@ func (cm *ContainerManager) CreateWorkerContainer(ctx context.Context, issueNumb
 	}
 
 	// Get repository configuration
-	config := cm.getRepositoryConfig(repository)
+	config := repoConfig
+	if config == nil {
+		config = cm.getRepositoryConfig(repository)
+	}
+	if config == nil {
+		return nil, fmt.Errorf("no configuration for repository %s and no default in %s", repository, cm.ConfigPath)
+	}
+	if config.Workspace == "" {
+		config.Workspace = "/workspace"
+	}
 	
 	// Ensure workspace parent directory exists (Host side)
 	if err := os.MkdirAll(cm.WorkspacesDir, 0755); err != nil {
@@ -139,8 +131,15 @@ func (cm *ContainerManager) CreateWorkerContainer(ctx context.Context, issueNumb
 	// Create session file path
 	sessionFile := filepath.Join(cm.SessionsDir, fmt.Sprintf("issue-%d.session", issueNumber))
 
+	// Generate Claude CLI auth files from templates and environment variables
+	authDir := containerAuthDir
+	if err := cm.generateContainerAuthFiles(authDir); err != nil {
+		log.Printf("Warning: failed to generate auth files, using fallback: %v", err)
+		authDir = ""
+	}
+
 	// Build Docker run command
-	dockerCmd := cm.buildDockerCommand(containerID, config, workspaceDir, repository)
+	dockerCmd := cm.buildDockerCommand(containerID, config, workspaceDir, repository, authDir)
 	
 	log.Printf("Creating worker container: %s", containerID)
 	log.Printf("Docker command: %s", strings.Join(dockerCmd, " "))
@@ -183,16 +182,32 @@ func (cm *ContainerManager) CreateWorkerContainer(ctx context.Context, issueNumb
 
 // getRepositoryConfig returns the configuration for a given repository
 func (cm *ContainerManager) getRepositoryConfig(repository string) *RepositoryConfig {
-	if config, exists := cm.RepoMapping.Repositories[repository]; exists {
-		return config
+	return cm.currentMapping().Lookup(repository)
+}
+
+// RenderDockerRun returns the docker run command CreateWorkerContainer would
+// use for an issue, without generating auth files or starting anything
+func RenderDockerRun(mapping *RepoMappingConfig, workspacesDir string, issueNumber int, repository string, repoConfig *RepositoryConfig) []string {
+	cm := &ContainerManager{RepoMapping: mapping, WorkspacesDir: workspacesDir}
+	config := repoConfig.Clone()
+	if config.Workspace == "" {
+		config.Workspace = "/workspace"
 	}
-	
-	log.Printf("No specific config found for repository %s, using default", repository)
-	return cm.RepoMapping.Default
+	workspaceDir := filepath.Join(workspacesDir, fmt.Sprintf("issue-%d", issueNumber))
+	return cm.buildDockerCommand(workerContainerName(issueNumber, repository), config, workspaceDir, repository, containerAuthDir)
+}
+
+// workerContainerName returns the container name of an issue's worker
+func workerContainerName(issueNumber int, repository string) string {
+	return fmt.Sprintf("claude-worker-%d-%s", issueNumber, strings.ReplaceAll(repository, "/", "-"))
 }
 
-// buildDockerCommand constructs the Docker run command for a worker container
-func (cm *ContainerManager) buildDockerCommand(containerID string, config *RepositoryConfig, workspaceDir, repository string) []string {
+// containerAuthDir holds the generated Claude CLI auth files mounted into workers
+const containerAuthDir = "/tmp/claude-auth-temp"
+
+// buildDockerCommand constructs the Docker run command for a worker container.
+// Without authDir an empty directory is mounted instead of the auth files.
+func (cm *ContainerManager) buildDockerCommand(containerID string, config *RepositoryConfig, workspaceDir, repository, authDir string) []string {
 	cmd := []string{
 		"docker", "run",
 		"--name", containerID,
@@ -200,52 +215,58 @@ func (cm *ContainerManager) buildDockerCommand(containerID string, config *Repos
 		"--rm", // Auto-remove when stopped
 	}
 
+	mapping := cm.currentMapping()
+	if mapping == nil {
+		mapping = &RepoMappingConfig{}
+	}
+
 	// Add resource limits
-	if cm.RepoMapping.ResourceLimits != nil {
-		if cm.RepoMapping.ResourceLimits.Memory != "" {
-			cmd = append(cmd, "--memory", cm.RepoMapping.ResourceLimits.Memory)
+	if limits := mapping.ResourceLimits; limits != nil {
+		if limits.Memory != "" {
+			cmd = append(cmd, "--memory", limits.Memory)
 		}
-		if cm.RepoMapping.ResourceLimits.CPU != "" {
-			cmd = append(cmd, "--cpus", cm.RepoMapping.ResourceLimits.CPU)
+		if limits.CPU != "" {
+			cmd = append(cmd, "--cpus", dockerCPUs(limits.CPU))
 		}
+		// disk is only enforced for Kubernetes workers: --storage-opt size
+		// fails on the common overlay2 setups
 	}
 
-	// Add security settings (relaxed for testing)
-	if cm.RepoMapping.Security != nil {
-		// Skip security restrictions for Docker-in-Docker testing
-		// if cm.RepoMapping.Security.NoNewPrivileges {
-		//     cmd = append(cmd, "--security-opt", "no-new-privileges:true")
-		// }
-		// Don't set user for now to avoid permission issues
-		// if cm.RepoMapping.Security.User != "" {
-		//     cmd = append(cmd, "--user", cm.RepoMapping.Security.User)
-		// }
-		// Skip capability restrictions for now
-		// for _, cap := range cm.RepoMapping.Security.Capabilities.Drop {
-		//     cmd = append(cmd, "--cap-drop", cap)
-		// }
-		for _, cap := range cm.RepoMapping.Security.Capabilities.Add {
-			cmd = append(cmd, "--cap-add", cap)
+	// Add security settings
+	if security := mapping.Security; security != nil {
+		if security.NoNewPrivileges {
+			cmd = append(cmd, "--security-opt", "no-new-privileges:true")
+		}
+		if security.ReadOnlyRoot {
+			cmd = append(cmd, "--read-only", "--tmpfs", "/tmp")
+		}
+		if security.Privileged {
+			cmd = append(cmd, "--privileged")
+		}
+		if security.User != "" {
+			cmd = append(cmd, "--user", security.User)
+		}
+		for _, capability := range security.Capabilities.Drop {
+			cmd = append(cmd, "--cap-drop", capability)
+		}
+		for _, capability := range security.Capabilities.Add {
+			cmd = append(cmd, "--cap-add", capability)
 		}
 	}
 
 	// Mount workspace directory (use absolute paths for Docker-in-Docker)
 	cmd = append(cmd, "-v", fmt.Sprintf("%s:%s", workspaceDir, config.Workspace))
 
-	// Generate and mount Claude CLI auth files from templates and environment variables
-	tempAuthDir := "/tmp/claude-auth-temp"
-	if err := cm.generateContainerAuthFiles(tempAuthDir); err != nil {
-		log.Printf("Warning: failed to generate auth files, using fallback: %v", err)
+	// Mount the generated Claude CLI auth files
+	if authDir == "" {
 		// Fallback: mount empty directory for safety
 		cmd = append(cmd, "-v", "/tmp/empty:/home/claude/.claude:ro")
 	} else {
 		// Mount generated auth structure to claude home 
 		// Structure: tempAuthDir/.claude.json -> /home/claude/.claude.json (read-write for CLI updates)
 		//           tempAuthDir/.claude/.credentials.json -> /home/claude/.claude/.credentials.json
-		cmd = append(cmd, "-v", fmt.Sprintf("%s/.claude.json:/home/claude/.claude.json:rw", tempAuthDir))
-		cmd = append(cmd, "-v", fmt.Sprintf("%s/.claude:/home/claude/.claude:rw", tempAuthDir))
-		
-		log.Printf("Mounting auth files: %s -> /home/claude/", tempAuthDir)
+		cmd = append(cmd, "-v", fmt.Sprintf("%s/.claude.json:/home/claude/.claude.json:rw", authDir))
+		cmd = append(cmd, "-v", fmt.Sprintf("%s/.claude:/home/claude/.claude:rw", authDir))
 	}
 
 	// Add environment variables
@@ -258,8 +279,13 @@ func (cm *ContainerManager) buildDockerCommand(containerID string, config *Repos
 	cmd = append(cmd, "-e", fmt.Sprintf("WORKSPACE=%s", config.Workspace))
 
 	// Add port mappings
-	for _, port := range config.Ports {
-		cmd = append(cmd, "-p", port)
+	for _, spec := range config.Ports {
+		args, err := portArgs(spec)
+		if err != nil {
+			log.Printf("Warning: ignoring port of %s: %v", repository, err)
+			continue
+		}
+		cmd = append(cmd, args...)
 	}
 
 	// Add working directory
@@ -284,6 +310,62 @@ func (cm *ContainerManager) ExecuteInContainer(ctx context.Context, containerID,
 	return string(output), nil
 }
 
+// ExecuteInContainerStream executes a command inside the worker container,
+// writing its stdout to the given writer as it is produced
+func (cm *ContainerManager) ExecuteInContainerStream(ctx context.Context, containerID, command string, stdout io.Writer) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", "exec", containerID, "sh", "-c", command)
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("failed to execute command in container: %w\nStderr: %s", err, stderr.String())
+		}
+		return fmt.Errorf("failed to execute command in container: %w", err)
+	}
+	return nil
+}
+
+// CloneRepository checks out a repository inside the worker container,
+// passing GITHUB_TOKEN through from the orchestrator's environment
+func (cm *ContainerManager) CloneRepository(ctx context.Context, containerID string, opts workspace.CloneOptions) (string, error) {
+	log.Printf("Cloning %s@%s into container %s:%s", opts.Repository, opts.Ref, containerID, opts.Dir)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-e", "GITHUB_TOKEN", containerID, "sh", "-c", workspace.CloneScript(opts))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("failed to clone %s@%s: %w\nStderr: %s", opts.Repository, opts.Ref, err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// PublishChanges commits a dirty workspace in the worker container to a
+// branch and pushes it
+func (cm *ContainerManager) PublishChanges(ctx context.Context, containerID string, opts workspace.PublishOptions) (workspace.PublishResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-e", "GITHUB_TOKEN", containerID, "sh", "-c", workspace.PublishScript(opts))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return workspace.PublishResult{}, fmt.Errorf("failed to push %s: %w\nStderr: %s", opts.Branch, err, stderr.String())
+	}
+	return workspace.ParsePublishOutput(stdout.String())
+}
+
+// ExecuteInContainerWithInput executes a command inside the worker container
+// with the given data on its stdin, e.g. to copy a file into the container
+func (cm *ContainerManager) ExecuteInContainerWithInput(ctx context.Context, containerID, command string, stdin io.Reader) (string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-i", containerID, "sh", "-c", command)
+	cmd.Stdin = stdin
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to execute command in container: %w\nOutput: %s", err, output)
+	}
+	return string(output), nil
+}
+
 // StopWorkerContainer stops and removes a worker container
 func (cm *ContainerManager) StopWorkerContainer(ctx context.Context, containerID string) error {
 	log.Printf("Stopping worker container: %s", containerID)
@@ -387,4 +469,28 @@ func (cm *ContainerManager) RefreshContainerAuth(ctx context.Context, containerI
 	
 	log.Printf("Successfully refreshed auth files for container: %s", containerID)
 	return nil
-}
\ No newline at end of file
+}
+
+// portArgs converts a repo-mapping port into docker run flags; ports
+// without a host part are only exposed
+func portArgs(spec string) ([]string, error) {
+	port, err := config.ParsePort(spec)
+	if err != nil {
+		return nil, err
+	}
+	if port.HostPort == 0 {
+		return []string{"--expose", fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol)}, nil
+	}
+	return []string{"-p", fmt.Sprintf("%d:%d/%s", port.HostPort, port.ContainerPort, port.Protocol)}, nil
+}
+
+// dockerCPUs converts a Kubernetes millicore value such as "500m" into the
+// decimal --cpus expects; other values are passed through
+func dockerCPUs(value string) string {
+	if millis, ok := strings.CutSuffix(value, "m"); ok {
+		if n, err := strconv.Atoi(millis); err == nil {
+			return strconv.FormatFloat(float64(n)/1000, 'f', -1, 64)
+		}
+	}
+	return value
+}
@@ -3,10 +3,15 @@ package kubernetes
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -21,68 +26,46 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/client-go/util/homedir"
+
+	"github.com/claude-automation/pkg/auth"
+	"github.com/claude-automation/pkg/config"
+	"github.com/claude-automation/pkg/workspace"
 )
 
 // PodManager manages worker pods for different repositories
 type PodManager struct {
-	clientset       *kubernetes.Clientset
+	clientset       kubernetes.Interface
 	config          *rest.Config
 	namespace       string
 	workspacesDir   string
 	sessionsDir     string
-	repoMapping     *RepoMappingConfig
-	activePods      map[string]*WorkerPod
+	repoMapping     atomic.Pointer[RepoMappingConfig] // replaced when repo-mapping.yaml is reloaded
+	podsMu          sync.Mutex
+	activePods      map[string]*WorkerPod // worker pods in the namespace by name; see SyncWorkerPods
+	manager         string                // managed-by label of the worker pods it creates; see SetManager
 	serviceAccount  string
 }
 
-// RepoMappingConfig represents the repository mapping configuration
-type RepoMappingConfig struct {
-	Repositories   map[string]*RepositoryConfig `yaml:"repositories"`
-	Default        *RepositoryConfig            `yaml:"default"`
-	ResourceLimits *ResourceLimits              `yaml:"resource_limits"`
-	Security       *SecurityConfig              `yaml:"security"`
-}
-
-// RepositoryConfig defines configuration for a specific repository
-type RepositoryConfig struct {
-	Image     string            `yaml:"image"`
-	Workspace string            `yaml:"workspace"`
-	Env       []string          `yaml:"env,omitempty"`
-	Ports     []int32           `yaml:"ports,omitempty"`
-	Commands  map[string]string `yaml:"commands,omitempty"`
-}
-
-// ResourceLimits defines pod resource constraints
-type ResourceLimits struct {
-	Memory  string `yaml:"memory"`
-	CPU     string `yaml:"cpu"`
-	Timeout string `yaml:"timeout"`
-}
-
-// SecurityConfig defines pod security settings
-type SecurityConfig struct {
-	ReadOnlyRoot    bool     `yaml:"read_only_root"`
-	NoPrivileged    bool     `yaml:"no_privileged"`
-	User            *int64   `yaml:"user,omitempty"`
-	Capabilities    CapConfig `yaml:"capabilities"`
-}
+// RepoMappingConfig is the repo-mapping.yaml file, shared with the Docker workers
+type RepoMappingConfig = config.RepoMapping
 
-type CapConfig struct {
-	Drop []corev1.Capability `yaml:"drop"`
-	Add  []corev1.Capability `yaml:"add"`
-}
+// RepositoryConfig is the worker configuration of a repository
+type RepositoryConfig = config.Repository
 
 // WorkerPod represents an active worker pod
 type WorkerPod struct {
-	ID           string
-	IssueNumber  int
-	Repository   string
-	PodName      string
-	Config       *RepositoryConfig
-	StartTime    time.Time
-	WorkspaceDir string
-	SessionFile  string
-	Status       corev1.PodPhase
+	ID              string
+	IssueNumber     int
+	Repository      string
+	IssueRepository string // repository of the issue; Repository is where the work is done
+	Manager         string // component that created it, from the managed-by label
+	PodName         string
+	JobName         string            // set for the pod of a worker Job
+	Config          *RepositoryConfig // nil for pods created by another process
+	StartTime       time.Time
+	WorkspaceDir    string
+	SessionFile     string
+	Status          corev1.PodPhase
 }
 
 // NewPodManager creates a new pod manager instance
@@ -122,6 +105,18 @@ func NewPodManager(namespace, workspacesDir, sessionsDir string) (*PodManager, e
 	return manager, nil
 }
 
+// SetManager names the component whose worker pods and Jobs this manager
+// creates, e.g. "monitor", so its cleanup leaves those of others alone
+func (pm *PodManager) SetManager(name string) {
+	pm.manager = name
+}
+
+// SetRepoMapping sets the repo-mapping.yaml whose resource limits and
+// security settings are applied to worker pods created from now on
+func (pm *PodManager) SetRepoMapping(mapping *RepoMappingConfig) {
+	pm.repoMapping.Store(mapping)
+}
+
 // verifyConnection verifies the connection to Kubernetes API
 func (pm *PodManager) verifyConnection() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -226,22 +221,33 @@ func (pm *PodManager) SetupServiceAccount(ctx context.Context) error {
 }
 
 // CreateWorkerPod creates a new worker pod for the given issue
-func (pm *PodManager) CreateWorkerPod(ctx context.Context, issueNumber int, repository string, config *RepositoryConfig) (*WorkerPod, error) {
-	podName := fmt.Sprintf("claude-worker-%d", issueNumber)
+func (pm *PodManager) CreateWorkerPod(ctx context.Context, issueNumber int, repository, issueRepository string, config *RepositoryConfig) (*WorkerPod, error) {
+	podName := workerPodName(issueNumber, repository, issueRepository)
 	
 	// Check if pod already exists
-	if existing, exists := pm.activePods[podName]; exists {
+	if existing, exists := pm.trackedPod(podName); exists {
+		if existing.Status != corev1.PodPending && existing.Status != corev1.PodRunning {
+			return nil, fmt.Errorf("pod %s of an earlier task is still present (phase %s)", podName, existing.Status)
+		}
 		log.Printf("Pod %s already exists for issue %d", podName, issueNumber)
+		if existing.Config == nil {
+			// Left by an earlier process for the same issue and repository
+			reused := *existing
+			reused.Config = config
+			pm.trackPod(&reused)
+			return &reused, nil
+		}
 		return existing, nil
 	}
 
-	// Generate authentication files for this pod
-	if err := pm.generateAndCreateAuthSecret(ctx, issueNumber); err != nil {
-		log.Printf("Warning: Failed to create auth secret: %v", err)
-	}
-
 	// Create pod specification
-	pod := pm.buildPodSpec(podName, issueNumber, repository, config)
+	pod := pm.buildPodSpec(podName, issueNumber, repository, issueRepository, config)
+
+	// Auth files of this task only; the pod waits for the secret to be mounted
+	authData := renderAuthSecret()
+	if authData != nil {
+		useAuthSecret(&pod.Spec, authSecretName(podName))
+	}
 	
 	log.Printf("Creating worker pod: %s for issue %d", podName, issueNumber)
 
@@ -251,40 +257,51 @@ func (pm *PodManager) CreateWorkerPod(ctx context.Context, issueNumber int, repo
 		return nil, fmt.Errorf("failed to create pod: %w", err)
 	}
 
+	if authData != nil {
+		owner := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: createdPod.Name, UID: createdPod.UID}
+		if err := pm.createAuthSecret(ctx, authSecretName(podName), pod.Labels, owner, authData); err != nil {
+			if err := pm.DeleteWorkerPod(context.WithoutCancel(ctx), createdPod.Name); err != nil {
+				log.Printf("Warning: Failed to cleanup pod %s: %v", createdPod.Name, err)
+			}
+			return nil, err
+		}
+	}
+
 	// Create workspace directory paths
 	workspaceDir := filepath.Join(pm.workspacesDir, fmt.Sprintf("issue-%d", issueNumber))
 	sessionFile := filepath.Join(pm.sessionsDir, fmt.Sprintf("issue-%d.session", issueNumber))
 
 	// Create worker pod object
 	worker := &WorkerPod{
-		ID:           podName,
-		IssueNumber:  issueNumber,
-		Repository:   repository,
-		PodName:      createdPod.Name,
-		Config:       config,
-		StartTime:    time.Now(),
-		WorkspaceDir: workspaceDir,
-		SessionFile:  sessionFile,
-		Status:       createdPod.Status.Phase,
-	}
-
-	pm.activePods[podName] = worker
+		ID:              podName,
+		IssueNumber:     issueNumber,
+		Repository:      repository,
+		IssueRepository: issueRepository,
+		Manager:         pm.manager,
+		PodName:         createdPod.Name,
+		Config:          config,
+		StartTime:       time.Now(),
+		WorkspaceDir:    workspaceDir,
+		SessionFile:     sessionFile,
+		Status:          createdPod.Status.Phase,
+	}
+
+	pm.trackPod(worker)
 	
 	log.Printf("Successfully created worker pod %s for issue %d", podName, issueNumber)
 	return worker, nil
 }
 
 // buildPodSpec constructs the Pod specification for a worker pod
-func (pm *PodManager) buildPodSpec(podName string, issueNumber int, repository string, config *RepositoryConfig) *corev1.Pod {
-	// Create valid Kubernetes label for repository
-	repoLabel := strings.ReplaceAll(repository, "/", "-")
-	repoLabel = strings.ReplaceAll(repoLabel, "_", "-")
-	
+func (pm *PodManager) buildPodSpec(podName string, issueNumber int, repository, issueRepository string, config *RepositoryConfig) *corev1.Pod {
 	labels := map[string]string{
 		"app":         "claude-automation",
 		"component":   "worker",
 		"issue":       fmt.Sprintf("%d", issueNumber),
-		"repository":  repoLabel,
+		"repository":  repositoryLabel(repository),
+	}
+	if pm.manager != "" {
+		labels[ManagerLabel] = pm.manager
 	}
 
 	// Environment variables
@@ -300,11 +317,30 @@ func (pm *PodManager) buildPodSpec(podName string, issueNumber int, repository s
 		env = append(env, corev1.EnvVar{Name: name, Value: value})
 	}
 
+	// The checkout lives in the workspace volume at the configured path
+	workspaceDir := config.Workspace
+	if workspaceDir == "" {
+		workspaceDir = "/workspace"
+	}
+
+	var ports []corev1.ContainerPort
+	for _, spec := range config.Ports {
+		port, err := parsePort(spec)
+		if err != nil {
+			log.Printf("Warning: ignoring port of %s: %v", repository, err)
+			continue
+		}
+		ports = append(ports, port)
+	}
+
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      podName,
 			Namespace: pm.namespace,
 			Labels:    labels,
+			Annotations: map[string]string{
+				IssueRepositoryAnnotation: issueRepository,
+			},
 		},
 		Spec: corev1.PodSpec{
 			ServiceAccountName: pm.serviceAccount,
@@ -316,11 +352,12 @@ func (pm *PodManager) buildPodSpec(podName string, issueNumber int, repository s
 					ImagePullPolicy: corev1.PullNever, // Use local images
 					Env:             env,
 					Command:         []string{"sh", "-c", "while true; do sleep 30; done"}, // Keep running
-					WorkingDir:      config.Workspace,
+					WorkingDir:      workspaceDir,
+					Ports:           ports,
 					VolumeMounts: []corev1.VolumeMount{
 						{
 							Name:      "workspace",
-							MountPath: "/workspace",
+							MountPath: workspaceDir,
 						},
 						{
 							Name:      "claude-temp",
@@ -355,7 +392,7 @@ func (pm *PodManager) buildPodSpec(podName string, issueNumber int, repository s
 					Name: "claude-auth",
 					VolumeSource: corev1.VolumeSource{
 						Secret: &corev1.SecretVolumeSource{
-							SecretName: "claude-auth",
+							SecretName: SharedAuthSecret,
 							Items: []corev1.KeyToPath{
 								{
 									Key:  "claude-config",
@@ -374,94 +411,48 @@ func (pm *PodManager) buildPodSpec(podName string, issueNumber int, repository s
 	}
 
 	// Apply resource limits if specified
-	if pm.repoMapping != nil && pm.repoMapping.ResourceLimits != nil {
-		resources := corev1.ResourceRequirements{
-			Limits:   corev1.ResourceList{},
-			Requests: corev1.ResourceList{},
-		}
-
-		if pm.repoMapping.ResourceLimits.Memory != "" {
-			// Parse and apply memory limit
-			if memoryQuantity, err := resource.ParseQuantity(pm.repoMapping.ResourceLimits.Memory); err == nil {
-				resources.Limits[corev1.ResourceMemory] = memoryQuantity
-				resources.Requests[corev1.ResourceMemory] = memoryQuantity
-			}
-		}
-
-		if pm.repoMapping.ResourceLimits.CPU != "" {
-			// Parse and apply CPU limit
-			if cpuQuantity, err := resource.ParseQuantity(pm.repoMapping.ResourceLimits.CPU); err == nil {
-				resources.Limits[corev1.ResourceCPU] = cpuQuantity
-				resources.Requests[corev1.ResourceCPU] = cpuQuantity
-			}
-		}
-
-		pod.Spec.Containers[0].Resources = resources
+	mapping := pm.repoMapping.Load()
+	if mapping != nil && mapping.ResourceLimits != nil {
+		pod.Spec.Containers[0].Resources = resourceRequirements(mapping.ResourceLimits)
 	}
 
 	// Apply security context if specified
-	if pm.repoMapping != nil && pm.repoMapping.Security != nil {
-		securityContext := &corev1.SecurityContext{}
-
-		if pm.repoMapping.Security.ReadOnlyRoot {
-			securityContext.ReadOnlyRootFilesystem = &pm.repoMapping.Security.ReadOnlyRoot
-		}
-
-		if !pm.repoMapping.Security.NoPrivileged {
-			privileged := false
-			securityContext.Privileged = &privileged
-		}
-
-		if pm.repoMapping.Security.User != nil {
-			securityContext.RunAsUser = pm.repoMapping.Security.User
-		}
-
-		pod.Spec.Containers[0].SecurityContext = securityContext
+	if mapping != nil && mapping.Security != nil {
+		pod.Spec.Containers[0].SecurityContext = securityContext(mapping.Security)
 	}
 
 	return pod
 }
 
-// WaitForPodReady waits for a pod to become ready
-func (pm *PodManager) WaitForPodReady(ctx context.Context, podName string, timeout time.Duration) error {
-	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	log.Printf("Waiting for pod %s to become ready...", podName)
+// ExecuteInPod executes a command inside the worker pod using Kubernetes exec API
+func (pm *PodManager) ExecuteInPod(ctx context.Context, podName, command string) (string, error) {
+	var stdout bytes.Buffer
+	if err := pm.ExecuteInPodStream(ctx, podName, command, &stdout); err != nil {
+		return "", err
+	}
 
-	for {
-		select {
-		case <-timeoutCtx.Done():
-			return fmt.Errorf("timeout waiting for pod %s to become ready", podName)
-		default:
-			pod, err := pm.clientset.CoreV1().Pods(pm.namespace).Get(ctx, podName, metav1.GetOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to get pod status: %w", err)
-			}
+	output := stdout.String()
+	log.Printf("Command executed successfully in pod %s, output length: %d bytes", podName, len(output))
+	return output, nil
+}
 
-			if pod.Status.Phase == corev1.PodRunning {
-				// Check if all containers are ready
-				allReady := true
-				for _, condition := range pod.Status.Conditions {
-					if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
-						allReady = false
-						break
-					}
-				}
-
-				if allReady {
-					log.Printf("Pod %s is ready", podName)
-					return nil
-				}
-			}
+// ExecuteInPodStream executes a command inside the worker pod, writing its
+// stdout to the given writer as it is produced
+func (pm *PodManager) ExecuteInPodStream(ctx context.Context, podName, command string, stdout io.Writer) error {
+	return pm.execInPod(ctx, podName, command, nil, stdout)
+}
 
-			time.Sleep(2 * time.Second)
-		}
+// ExecuteInPodWithInput executes a command inside the worker pod with the
+// given data on its stdin, e.g. to copy a file into the pod
+func (pm *PodManager) ExecuteInPodWithInput(ctx context.Context, podName, command string, stdin io.Reader) (string, error) {
+	var stdout bytes.Buffer
+	if err := pm.execInPod(ctx, podName, command, stdin, &stdout); err != nil {
+		return "", err
 	}
+	return stdout.String(), nil
 }
 
-// ExecuteInPod executes a command inside the worker pod using Kubernetes exec API
-func (pm *PodManager) ExecuteInPod(ctx context.Context, podName, command string) (string, error) {
+func (pm *PodManager) execInPod(ctx context.Context, podName, command string, stdin io.Reader, stdout io.Writer) error {
 	log.Printf("Executing command in pod %s: %s", podName, command)
 	
 	// Import required packages for exec API
@@ -474,6 +465,7 @@ func (pm *PodManager) ExecuteInPod(ctx context.Context, podName, command string)
 	// Exec options - using sh to execute the command
 	req.VersionedParams(&corev1.PodExecOptions{
 		Command: []string{"sh", "-c", command},
+		Stdin:   stdin != nil,
 		Stdout:  true,
 		Stderr:  true,
 		TTY:     false,
@@ -482,15 +474,16 @@ func (pm *PodManager) ExecuteInPod(ctx context.Context, podName, command string)
 	// Create SPDY executor for streaming
 	exec, err := remotecommand.NewSPDYExecutor(pm.config, "POST", req.URL())
 	if err != nil {
-		return "", fmt.Errorf("failed to create executor: %w", err)
+		return fmt.Errorf("failed to create executor: %w", err)
 	}
 
-	// Create buffers to capture output
-	var stdout, stderr bytes.Buffer
+	// Capture stderr for error reporting
+	var stderr bytes.Buffer
 	
-	// Execute the command
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdout: &stdout,
+	// Execute the command; cancelling ctx aborts the stream
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
 		Stderr: &stderr,
 		Tty:    false,
 	})
@@ -498,14 +491,34 @@ func (pm *PodManager) ExecuteInPod(ctx context.Context, podName, command string)
 	if err != nil {
 		stderrOutput := stderr.String()
 		if stderrOutput != "" {
-			return "", fmt.Errorf("command execution failed: %w\nStderr: %s", err, stderrOutput)
+			return fmt.Errorf("command execution failed: %w\nStderr: %s", err, stderrOutput)
 		}
-		return "", fmt.Errorf("command execution failed: %w", err)
+		return fmt.Errorf("command execution failed: %w", err)
 	}
 
-	output := stdout.String()
-	log.Printf("Command executed successfully in pod %s, output length: %d bytes", podName, len(output))
-	return output, nil
+	return nil
+}
+
+// CloneRepository checks out a repository inside the worker pod. The pod
+// needs GITHUB_TOKEN in its environment for private repositories.
+func (pm *PodManager) CloneRepository(ctx context.Context, podName string, opts workspace.CloneOptions) (string, error) {
+	log.Printf("Cloning %s@%s into pod %s:%s", opts.Repository, opts.Ref, podName, opts.Dir)
+
+	output, err := pm.ExecuteInPod(ctx, podName, workspace.CloneScript(opts))
+	if err != nil {
+		return output, fmt.Errorf("failed to clone %s@%s: %w", opts.Repository, opts.Ref, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// PublishChanges commits a dirty workspace in the worker pod to a branch
+// and pushes it
+func (pm *PodManager) PublishChanges(ctx context.Context, podName string, opts workspace.PublishOptions) (workspace.PublishResult, error) {
+	output, err := pm.ExecuteInPod(ctx, podName, workspace.PublishScript(opts))
+	if err != nil {
+		return workspace.PublishResult{}, fmt.Errorf("failed to push %s: %w", opts.Branch, err)
+	}
+	return workspace.ParsePublishOutput(output)
 }
 
 // DeleteWorkerPod stops and removes a worker pod
@@ -519,14 +532,18 @@ func (pm *PodManager) DeleteWorkerPod(ctx context.Context, podName string) error
 	}
 
 	// Remove from active pods
-	delete(pm.activePods, podName)
+	pm.untrackPod(podName)
 	
 	log.Printf("Successfully deleted worker pod: %s", podName)
 	return nil
 }
 
-// GetActivePods returns a list of currently active pods
+// GetActivePods returns a list of currently active pods, including those
+// found by SyncWorkerPods
 func (pm *PodManager) GetActivePods() []*WorkerPod {
+	pm.podsMu.Lock()
+	defer pm.podsMu.Unlock()
+
 	pods := make([]*WorkerPod, 0, len(pm.activePods))
 	for _, pod := range pm.activePods {
 		pods = append(pods, pod)
@@ -534,20 +551,32 @@ func (pm *PodManager) GetActivePods() []*WorkerPod {
 	return pods
 }
 
-// CleanupStalePods removes pods that have been running too long
-func (pm *PodManager) CleanupStalePods(ctx context.Context, maxAge time.Duration) error {
-	now := time.Now()
-	var stalePods []string
-	
-	for id, pod := range pm.activePods {
-		if now.Sub(pod.StartTime) > maxAge {
-			stalePods = append(stalePods, id)
+// ManagedPods returns the active pods created by this manager's component,
+// including those left by an earlier process
+func (pm *PodManager) ManagedPods() []*WorkerPod {
+	var pods []*WorkerPod
+	for _, pod := range pm.GetActivePods() {
+		if pod.Manager == pm.manager {
+			pods = append(pods, pod)
 		}
 	}
+	return pods
+}
+
+// CleanupStalePods removes pods of this manager's component that have been
+// running too long, unless inUse reports that a running task still uses
+// them. The pod of a worker Job is removed with its Job.
+func (pm *PodManager) CleanupStalePods(ctx context.Context, maxAge time.Duration, inUse func(*WorkerPod) bool) error {
+	now := time.Now()
 	
-	for _, id := range stalePods {
-		if err := pm.DeleteWorkerPod(ctx, id); err != nil {
-			log.Printf("Failed to cleanup stale pod %s: %v", id, err)
+	for _, pod := range pm.ManagedPods() {
+		age := now.Sub(pod.StartTime)
+		if age <= maxAge || inUse(pod) {
+			continue
+		}
+		reason := fmt.Sprintf("age %v exceeds max pod age %v", age.Round(time.Second), maxAge)
+		if err := pm.DeleteWorker(ctx, pod, reason); err != nil {
+			log.Printf("Failed to cleanup stale pod %s: %v", pod.PodName, err)
 		}
 	}
 	
@@ -581,50 +610,137 @@ func (pm *PodManager) GetPodLogs(ctx context.Context, podName string) (string, e
 	return string(logBytes), nil
 }
 
-// generateAndCreateAuthSecret generates authentication files and creates Kubernetes secret
-func (pm *PodManager) generateAndCreateAuthSecret(ctx context.Context, issueNumber int) error {
-	// Import auth package for authentication file generation
-	// This requires adding the auth package import at the top
-	
-	// For now, create a basic secret - this would be enhanced with actual auth generation
-	secretName := "claude-auth"
-	
-	// Check if secret already exists
-	_, err := pm.clientset.CoreV1().Secrets(pm.namespace).Get(ctx, secretName, metav1.GetOptions{})
-	if err == nil {
-		log.Printf("Auth secret %s already exists", secretName)
+// SharedAuthSecret holds the auth files of workers when the manager cannot
+// render their own from the CLAUDE_* environment variables
+const SharedAuthSecret = "claude-auth"
+
+// renderAuthSecret renders the Claude CLI auth files of a worker, or returns
+// nil when the worker has to mount SharedAuthSecret instead
+func renderAuthSecret() map[string][]byte {
+	files, err := auth.RenderAuthFiles()
+	if err != nil {
+		log.Printf("Warning: cannot render auth files, the worker uses the %s secret: %v", SharedAuthSecret, err)
 		return nil
 	}
-	
-	// Create basic secret structure for testing
-	// In full implementation, this would use pkg/auth to generate actual auth files
+	return map[string][]byte{
+		"claude-config": files.ClaudeConfig,
+		"credentials":   files.Credentials,
+	}
+}
+
+// authSecretName is the per-task auth secret of a worker pod or Job
+func authSecretName(name string) string {
+	return name + "-auth"
+}
+
+// useAuthSecret makes a worker mount the given auth secret
+func useAuthSecret(spec *corev1.PodSpec, secretName string) {
+	for _, volume := range spec.Volumes {
+		if volume.Name == "claude-auth" && volume.Secret != nil {
+			volume.Secret.SecretName = secretName
+		}
+	}
+}
+
+// createAuthSecret stores the auth files of a worker in a secret owned by the
+// worker pod or Job, so Kubernetes garbage collection deletes it together
+// with the worker. A secret left by an earlier worker of the same name is
+// taken over.
+func (pm *PodManager) createAuthSecret(ctx context.Context, name string, labels map[string]string, owner metav1.OwnerReference, data map[string][]byte) error {
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: pm.namespace,
-			Labels: map[string]string{
-				"app":       "claude-automation",
-				"component": "auth",
-			},
+			Name:            name,
+			Namespace:       pm.namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{owner},
 		},
 		Type: corev1.SecretTypeOpaque,
-		Data: map[string][]byte{
-			"claude-config": []byte(`{"version": "1.0", "auth": {"method": "oauth"}}`),
-			"credentials":   []byte(`{"token": "placeholder", "expires": "2025-12-31"}`),
-		},
+		Data: data,
+	}
+
+	secrets := pm.clientset.CoreV1().Secrets(pm.namespace)
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		var existing *corev1.Secret
+		if existing, err = secrets.Get(ctx, name, metav1.GetOptions{}); err == nil {
+			secret.ResourceVersion = existing.ResourceVersion
+			_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		}
 	}
-	
-	_, err = pm.clientset.CoreV1().Secrets(pm.namespace).Create(ctx, secret, metav1.CreateOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to create auth secret: %w", err)
+		return fmt.Errorf("failed to create auth secret %s: %w", name, err)
+	}
+
+	log.Printf("Created auth secret %s owned by %s %s", name, owner.Kind, owner.Name)
+	return nil
+}
+
+// GetConfigMapValue returns a single key from a ConfigMap in the manager's
+// namespace. found is false when the ConfigMap or key does not exist.
+func (pm *PodManager) GetConfigMapValue(ctx context.Context, name, key string) (value string, found bool, err error) {
+	configMap, err := pm.clientset.CoreV1().ConfigMaps(pm.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to get configmap %s: %w", name, err)
+	}
+
+	value, found = configMap.Data[key]
+	return value, found, nil
+}
+
+// SetConfigMapValue stores a single key in a ConfigMap, creating the
+// ConfigMap if it does not exist yet
+func (pm *PodManager) SetConfigMapValue(ctx context.Context, name, key, value string) error {
+	configMaps := pm.clientset.CoreV1().ConfigMaps(pm.namespace)
+
+	configMap, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: pm.namespace,
+				Labels: map[string]string{
+					"app":       "claude-automation",
+					"component": "monitor",
+				},
+			},
+			Data: map[string]string{key: value},
+		}
+		if _, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create configmap %s: %w", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get configmap %s: %w", name, err)
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[key] = value
+
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update configmap %s: %w", name, err)
 	}
-	
-	log.Printf("Created auth secret for pod authentication")
 	return nil
 }
 
 // Helper functions
 
+// workerPodName derives the pod name from the issue, its repository and the
+// repository the work is done in, so that issues with the same number in
+// different repositories do not collide even when they target the same one
+func workerPodName(issueNumber int, repository, issueRepository string) string {
+	if issueRepository == "" {
+		issueRepository = repository
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(issueRepository) + "\n" + strings.ToLower(repository)))
+	return fmt.Sprintf("claude-worker-%d-%s", issueNumber, hex.EncodeToString(sum[:4]))
+}
+
 func parseEnvironmentVariable(envVar string) (name, value string) {
 	// Parse environment variable in format "NAME=value"
 	parts := strings.SplitN(envVar, "=", 2)
@@ -632,4 +748,136 @@ func parseEnvironmentVariable(envVar string) (name, value string) {
 		return parts[0], parts[1]
 	}
 	return envVar, ""
-}
\ No newline at end of file
+}
+
+// RenderWorkerPod returns the Pod CreateWorkerPod would create for an issue,
+// without contacting the cluster
+func RenderWorkerPod(namespace string, mapping *RepoMappingConfig, issueNumber int, repository, issueRepository string, config *RepositoryConfig) *corev1.Pod {
+	pm := &PodManager{
+		namespace:      namespace,
+		serviceAccount: "claude-worker",
+	}
+	pm.SetRepoMapping(mapping)
+	pod := pm.buildPodSpec(workerPodName(issueNumber, repository, issueRepository), issueNumber, repository, issueRepository, config)
+	pod.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}
+	return pod
+}
+
+// CheckResourceLimits reports the resource_limits values that are not valid
+// Kubernetes quantities (Docker sizes such as "1g" are accepted)
+func CheckResourceLimits(limits *config.ResourceLimits) []error {
+	if limits == nil {
+		return nil
+	}
+	var errs []error
+	for _, limit := range []struct {
+		name  corev1.ResourceName
+		value string
+	}{
+		{corev1.ResourceMemory, limits.Memory},
+		{corev1.ResourceCPU, limits.CPU},
+		{corev1.ResourceEphemeralStorage, limits.Disk},
+	} {
+		if limit.value == "" {
+			continue
+		}
+		if _, err := parseQuantity(limit.name, limit.value); err != nil {
+			errs = append(errs, fmt.Errorf("resource_limits: %w", err))
+		}
+	}
+	return errs
+}
+
+// resourceRequirements converts resource_limits into container limits.
+// Requests equal limits so the worker gets a guaranteed share.
+func resourceRequirements(limits *config.ResourceLimits) corev1.ResourceRequirements {
+	resources := corev1.ResourceRequirements{
+		Limits:   corev1.ResourceList{},
+		Requests: corev1.ResourceList{},
+	}
+
+	for name, value := range map[corev1.ResourceName]string{
+		corev1.ResourceMemory:           limits.Memory,
+		corev1.ResourceCPU:              limits.CPU,
+		corev1.ResourceEphemeralStorage: limits.Disk,
+	} {
+		if value == "" {
+			continue
+		}
+		quantity, err := parseQuantity(name, value)
+		if err != nil {
+			log.Printf("Warning: ignoring resource limit: %v", err)
+			continue
+		}
+		resources.Limits[name] = quantity
+		resources.Requests[name] = quantity
+	}
+	return resources
+}
+
+// dockerSizeSuffixes maps Docker size units to Kubernetes binary suffixes; a
+// lowercase "m" would otherwise be read as milli
+var dockerSizeSuffixes = map[string]string{"b": "", "k": "Ki", "m": "Mi", "g": "Gi", "t": "Ti"}
+
+// parseQuantity parses a Kubernetes quantity, accepting Docker notation such
+// as "512m" or "1g" for sizes
+func parseQuantity(name corev1.ResourceName, value string) (resource.Quantity, error) {
+	if name != corev1.ResourceCPU && len(value) > 1 {
+		number, unit := value[:len(value)-1], value[len(value)-1:]
+		if suffix, ok := dockerSizeSuffixes[strings.ToLower(unit)]; ok && strings.Trim(number, "0123456789.") == "" {
+			value = number + suffix
+		}
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return quantity, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	return quantity, nil
+}
+
+// securityContext converts the security settings into a container security context
+func securityContext(security *config.Security) *corev1.SecurityContext {
+	privileged := security.Privileged
+	context := &corev1.SecurityContext{
+		Privileged: &privileged,
+	}
+
+	if security.ReadOnlyRoot {
+		readOnly := true
+		context.ReadOnlyRootFilesystem = &readOnly
+	}
+	if security.NoNewPrivileges {
+		allowEscalation := false
+		context.AllowPrivilegeEscalation = &allowEscalation
+	}
+
+	uid, gid, err := security.UserGroup()
+	if err != nil {
+		log.Printf("Warning: ignoring security user: %v", err)
+	}
+	context.RunAsUser = uid
+	context.RunAsGroup = gid
+
+	if len(security.Capabilities.Drop) > 0 || len(security.Capabilities.Add) > 0 {
+		context.Capabilities = &corev1.Capabilities{}
+		for _, capability := range security.Capabilities.Drop {
+			context.Capabilities.Drop = append(context.Capabilities.Drop, corev1.Capability(capability))
+		}
+		for _, capability := range security.Capabilities.Add {
+			context.Capabilities.Add = append(context.Capabilities.Add, corev1.Capability(capability))
+		}
+	}
+	return context
+}
+
+// parsePort converts a repo-mapping port into a container port
+func parsePort(spec string) (corev1.ContainerPort, error) {
+	port, err := config.ParsePort(spec)
+	if err != nil {
+		return corev1.ContainerPort{}, err
+	}
+	return corev1.ContainerPort{
+		ContainerPort: port.ContainerPort,
+		Protocol:      corev1.Protocol(strings.ToUpper(port.Protocol)),
+	}, nil
+}
@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestWorkerPodName(t *testing.T) {
+	type worker struct {
+		issueNumber     int
+		repository      string
+		issueRepository string
+	}
+	tests := []struct {
+		name     string
+		a, b     worker
+		wantSame bool
+	}{
+		{
+			name: "same issue number in different repositories",
+			a:    worker{5, "org/a", "org/a"},
+			b:    worker{5, "org/b", "org/b"},
+		},
+		{
+			name: "same issue number in different repositories with the same target",
+			a:    worker{5, "org/c", "org/a"},
+			b:    worker{5, "org/c", "org/b"},
+		},
+		{
+			name: "one issue targeting different repositories",
+			a:    worker{5, "org/a", "org/a"},
+			b:    worker{5, "org/c", "org/a"},
+		},
+		{
+			name: "issue and target repositories swapped",
+			a:    worker{5, "org/a", "org/b"},
+			b:    worker{5, "org/b", "org/a"},
+		},
+		{
+			name: "different issues",
+			a:    worker{5, "org/a", "org/a"},
+			b:    worker{6, "org/a", "org/a"},
+		},
+		{
+			name:     "repository names ignore case",
+			a:        worker{5, "Org/C", "Org/A"},
+			b:        worker{5, "org/c", "org/a"},
+			wantSame: true,
+		},
+		{
+			name:     "issue repository defaults to the target",
+			a:        worker{5, "org/a", ""},
+			b:        worker{5, "org/a", "org/a"},
+			wantSame: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := workerPodName(tt.a.issueNumber, tt.a.repository, tt.a.issueRepository)
+			b := workerPodName(tt.b.issueNumber, tt.b.repository, tt.b.issueRepository)
+			if same := a == b; same != tt.wantSame {
+				t.Errorf("workerPodName(%+v) = %s, workerPodName(%+v) = %s, want same = %v", tt.a, a, tt.b, b, tt.wantSame)
+			}
+			for _, name := range []string{a, b} {
+				// Job pods get a suffix, so names must stay well below 63 characters
+				if errs := validation.IsDNS1123Label(name); len(errs) > 0 || len(name) > 52 {
+					t.Errorf("%s is not a valid worker name: %v", name, errs)
+				}
+			}
+		})
+	}
+}
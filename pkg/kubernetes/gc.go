@@ -0,0 +1,31 @@
+package kubernetes
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// IssueRepositoryAnnotation records the repository of the issue a worker
+// pod works on; its repository label is the repository the work is done in
+const IssueRepositoryAnnotation = "claude-automation/issue-repository"
+
+// ManagerLabel names the component, such as "orchestrator", that created a
+// worker pod or Job
+const ManagerLabel = "managed-by"
+
+// DeleteWorker removes a worker pod, or the Job it belongs to, and logs why
+func (pm *PodManager) DeleteWorker(ctx context.Context, worker *WorkerPod, reason string) error {
+	if worker.JobName != "" {
+		log.Printf("Removing worker job %s of issue %s#%d: %s", worker.JobName, worker.IssueRepository, worker.IssueNumber, reason)
+		return pm.DeleteWorkerJob(ctx, worker.JobName)
+	}
+	log.Printf("Removing worker pod %s of issue %s#%d: %s", worker.PodName, worker.IssueRepository, worker.IssueNumber, reason)
+	return pm.DeleteWorkerPod(ctx, worker.PodName)
+}
+
+// repositoryLabel turns a repository name into a valid label value
+func repositoryLabel(repository string) string {
+	label := strings.ReplaceAll(repository, "/", "-")
+	return strings.ReplaceAll(label, "_", "-")
+}
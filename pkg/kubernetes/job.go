@@ -0,0 +1,341 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Worker modes selected with WORKER_MODE
+const (
+	WorkerModePod = "pod" // a long-running pod the task is executed in step by step
+	WorkerModeJob = "job" // a Job whose container runs the whole task as its entrypoint
+)
+
+// TerminationMessagePath is where a worker Job writes its result; the
+// kubelet reports it in the container's terminated state
+const TerminationMessagePath = corev1.TerminationMessagePathDefault
+
+// jobPollInterval is how often a running worker Job is checked
+const jobPollInterval = 2 * time.Second
+
+// WorkerModeFromEnv reads WORKER_MODE (default pod)
+func WorkerModeFromEnv() (string, error) {
+	switch mode := os.Getenv("WORKER_MODE"); mode {
+	case "", WorkerModePod:
+		return WorkerModePod, nil
+	case WorkerModeJob:
+		return WorkerModeJob, nil
+	default:
+		return "", fmt.Errorf("invalid WORKER_MODE %q (expected %s or %s)", mode, WorkerModePod, WorkerModeJob)
+	}
+}
+
+// JobOptions bound a worker Job
+type JobOptions struct {
+	ActiveDeadline   time.Duration // activeDeadlineSeconds; 0 leaves the Job unbounded
+	BackoffLimit     int32
+	TTLAfterFinished time.Duration // ttlSecondsAfterFinished; 0 keeps finished Jobs
+}
+
+// JobOptionsFromEnv reads WORKER_JOB_BACKOFF_LIMIT (default 0, so a failed
+// run is reported instead of repeated) and WORKER_JOB_TTL (default 1h). The
+// deadline comes from the task timeout.
+func JobOptionsFromEnv() (JobOptions, error) {
+	opts := JobOptions{TTLAfterFinished: time.Hour}
+
+	if value := os.Getenv("WORKER_JOB_BACKOFF_LIMIT"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || limit < 0 {
+			return opts, fmt.Errorf("invalid WORKER_JOB_BACKOFF_LIMIT %q (expected 0 or a positive integer)", value)
+		}
+		opts.BackoffLimit = int32(limit)
+	}
+	if value := os.Getenv("WORKER_JOB_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return opts, fmt.Errorf("invalid WORKER_JOB_TTL %q (expected a duration such as 1h)", value)
+		}
+		opts.TTLAfterFinished = ttl
+	}
+	return opts, nil
+}
+
+// WorkerJob represents a worker running as a Job
+type WorkerJob struct {
+	JobName     string
+	IssueNumber int
+	Repository  string
+	Config      *RepositoryConfig
+	StartTime   time.Time
+}
+
+// JobStatus is the outcome of a finished worker Job
+type JobStatus struct {
+	PodName            string // the Job's last pod
+	Succeeded          bool
+	Reason             string // of a failed Job, e.g. DeadlineExceeded or BackoffLimitExceeded
+	Message            string
+	ExitCode           int32
+	TerminationMessage string // written by the worker, or the tail of its log when it failed without one
+}
+
+// DeadlineExceeded reports whether the Job was stopped by its activeDeadlineSeconds
+func (s *JobStatus) DeadlineExceeded() bool {
+	return s.Reason == batchv1.JobReasonDeadlineExceeded
+}
+
+// CreateWorkerJob creates a Job for the given issue whose worker container,
+// configured like CreateWorkerPod's, runs script as its entrypoint
+func (pm *PodManager) CreateWorkerJob(ctx context.Context, issueNumber int, repository, issueRepository string, config *RepositoryConfig, script string, opts JobOptions) (*WorkerJob, error) {
+	job := pm.buildJobSpec(workerPodName(issueNumber, repository, issueRepository), issueNumber, repository, issueRepository, config, script, opts)
+
+	// Auth files of this task only, deleted together with the Job
+	authData := renderAuthSecret()
+	if authData != nil {
+		useAuthSecret(&job.Spec.Template.Spec, authSecretName(job.Name))
+	}
+	log.Printf("Creating worker job: %s for issue %d", job.Name, issueNumber)
+
+	created, err := pm.clientset.BatchV1().Jobs(pm.namespace).Create(ctx, job, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// A finished Job of an earlier task is kept until WORKER_JOB_TTL
+		if err = pm.removeFinishedJob(ctx, job.Name); err == nil {
+			created, err = pm.clientset.BatchV1().Jobs(pm.namespace).Create(ctx, job, metav1.CreateOptions{})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	if authData != nil {
+		owner := metav1.OwnerReference{APIVersion: "batch/v1", Kind: "Job", Name: created.Name, UID: created.UID}
+		if err := pm.createAuthSecret(ctx, authSecretName(job.Name), job.Labels, owner, authData); err != nil {
+			if err := pm.DeleteWorkerJob(context.WithoutCancel(ctx), created.Name); err != nil {
+				log.Printf("Warning: Failed to cleanup job %s: %v", created.Name, err)
+			}
+			return nil, err
+		}
+	}
+
+	return &WorkerJob{
+		JobName:     created.Name,
+		IssueNumber: issueNumber,
+		Repository:  repository,
+		Config:      config,
+		StartTime:   time.Now(),
+	}, nil
+}
+
+// removeFinishedJob deletes a finished Job of an earlier task so its name
+// can be reused and waits until it is gone. A Job that is still running
+// belongs to another task and is kept.
+func (pm *PodManager) removeFinishedJob(ctx context.Context, jobName string) error {
+	_, done, err := pm.jobStatus(ctx, jobName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !done {
+		return fmt.Errorf("job %s of an earlier task is still running", jobName)
+	}
+
+	log.Printf("Removing finished job %s of an earlier task", jobName)
+	if err := pm.DeleteWorkerJob(ctx, jobName); err != nil {
+		return err
+	}
+	for {
+		_, err := pm.clientset.BatchV1().Jobs(pm.namespace).Get(ctx, jobName, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get job %s: %w", jobName, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// buildJobSpec wraps the worker pod specification in a Job
+func (pm *PodManager) buildJobSpec(name string, issueNumber int, repository, issueRepository string, config *RepositoryConfig, script string, opts JobOptions) *batchv1.Job {
+	pod := pm.buildPodSpec(name, issueNumber, repository, issueRepository, config)
+	container := &pod.Spec.Containers[0]
+	container.Command = []string{"sh", "-c", script}
+	container.TerminationMessagePath = TerminationMessagePath
+	container.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+
+	backoffLimit := opts.BackoffLimit
+	job := &batchv1.Job{
+		ObjectMeta: pod.ObjectMeta,
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: pod.Labels, Annotations: pod.Annotations},
+				Spec:       pod.Spec,
+			},
+		},
+	}
+	if opts.ActiveDeadline > 0 {
+		deadline := int64(opts.ActiveDeadline.Seconds())
+		job.Spec.ActiveDeadlineSeconds = &deadline
+	}
+	if opts.TTLAfterFinished > 0 {
+		ttl := int32(opts.TTLAfterFinished.Seconds())
+		job.Spec.TTLSecondsAfterFinished = &ttl
+	}
+	return job
+}
+
+// FollowWorkerJob writes the logs of the Job's pods to stdout as they are
+// produced and returns the Job's status once it finished. When the Job is
+// retried the logs of every attempt are written in turn. A pod that cannot
+// start ends it early with a PodStartError.
+func (pm *PodManager) FollowWorkerJob(ctx context.Context, jobName string, stdout io.Writer) (*JobStatus, error) {
+	followed := make(map[string]bool)
+	for {
+		status, done, err := pm.jobStatus(ctx, jobName)
+		if err != nil {
+			return nil, err
+		}
+
+		pods, err := pm.jobPods(ctx, jobName)
+		if err != nil {
+			return nil, err
+		}
+		var next *corev1.Pod
+		for i := range pods {
+			pod := &pods[i]
+			if pod.Status.Phase == corev1.PodPending {
+				// Without this the Job would wait for its deadline
+				if failure := podStartFailure(pod); failure != nil && !done {
+					failure.Events = pm.podEvents(ctx, pod.Name)
+					return nil, failure
+				}
+				continue
+			}
+			if !followed[pod.Name] {
+				next = pod
+				break
+			}
+		}
+		if next != nil {
+			followed[next.Name] = true
+			if err := pm.followPodLogs(ctx, next.Name, stdout); err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				// A pod that failed before its container started has no log
+				log.Printf("Warning: %v", err)
+			}
+			continue
+		}
+
+		if done {
+			return status, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// jobStatus reports whether the Job finished and, if it did, how
+func (pm *PodManager) jobStatus(ctx context.Context, jobName string) (*JobStatus, bool, error) {
+	job, err := pm.clientset.BatchV1().Jobs(pm.namespace).Get(ctx, jobName, metav1.GetOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get job status: %w", err)
+	}
+
+	status := &JobStatus{}
+	done := false
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.Succeeded, done = true, true
+		case batchv1.JobFailed:
+			status.Reason, status.Message, done = condition.Reason, condition.Message, true
+		}
+	}
+	if !done {
+		return nil, false, nil
+	}
+
+	pods, err := pm.jobPods(ctx, jobName)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(pods) > 0 {
+		last := pods[len(pods)-1]
+		status.PodName = last.Name
+		for _, container := range last.Status.ContainerStatuses {
+			if terminated := container.State.Terminated; terminated != nil {
+				status.ExitCode = terminated.ExitCode
+				status.TerminationMessage = terminated.Message
+			}
+		}
+	}
+	return status, true, nil
+}
+
+// jobPods returns the pods of a Job, oldest first
+func (pm *PodManager) jobPods(ctx context.Context, jobName string) ([]corev1.Pod, error) {
+	pods, err := pm.clientset.CoreV1().Pods(pm.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + jobName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods of job %s: %w", jobName, err)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+	return pods.Items, nil
+}
+
+// followPodLogs copies a pod's log to w until the pod's container exits
+func (pm *PodManager) followPodLogs(ctx context.Context, podName string, w io.Writer) error {
+	logs, err := pm.clientset.CoreV1().Pods(pm.namespace).GetLogs(podName, &corev1.PodLogOptions{Follow: true}).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to follow logs of pod %s: %w", podName, err)
+	}
+	defer logs.Close()
+
+	if _, err := io.Copy(w, logs); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read logs of pod %s: %w", podName, err)
+	}
+	return nil
+}
+
+// DeleteWorkerJob removes a worker Job together with its pods
+func (pm *PodManager) DeleteWorkerJob(ctx context.Context, jobName string) error {
+	log.Printf("Deleting worker job: %s", jobName)
+
+	propagation := metav1.DeletePropagationBackground
+	err := pm.clientset.BatchV1().Jobs(pm.namespace).Delete(ctx, jobName, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// earlierJob is a Job left by an earlier task of issue 5 in org/app
+func earlierJob(condition batchv1.JobConditionType) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:      workerPodName(5, "org/app", "org/app"),
+		Namespace: "default",
+		UID:       "earlier",
+	}}
+	if condition != "" {
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+	}
+	return job
+}
+
+func TestCreateWorkerJobReplacesFinishedJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		earlier *batchv1.Job
+		wantErr bool
+	}{
+		{name: "no earlier job"},
+		{name: "completed job", earlier: earlierJob(batchv1.JobComplete)},
+		{name: "failed job", earlier: earlierJob(batchv1.JobFailed)},
+		{name: "running job", earlier: earlierJob(""), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewClientset()
+			if tt.earlier != nil {
+				clientset = fake.NewClientset(tt.earlier)
+			}
+			pm := &PodManager{clientset: clientset, namespace: "default", serviceAccount: "claude-worker"}
+			pm.SetRepoMapping(&RepoMappingConfig{})
+
+			job, err := pm.CreateWorkerJob(context.Background(), 5, "org/app", "org/app", &RepositoryConfig{}, "true", JobOptions{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("CreateWorkerJob succeeded while the earlier job is running, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateWorkerJob failed: %v", err)
+			}
+
+			created, err := clientset.BatchV1().Jobs("default").Get(context.Background(), job.JobName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("job %s was not created: %v", job.JobName, err)
+			}
+			if created.UID == "earlier" || len(created.Status.Conditions) > 0 {
+				t.Errorf("job %s is still the earlier one", job.JobName)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WorkerSelector selects the worker pods created by any PodManager,
+// including those of worker Jobs
+const WorkerSelector = "app=claude-automation,component=worker"
+
+// podSyncTimeout bounds how long SyncWorkerPods waits for the first list
+const podSyncTimeout = 30 * time.Second
+
+// SyncWorkerPods rebuilds the active pods from the worker pods in the
+// namespace, so pods left by an earlier process are cleaned up too, and
+// keeps them current with an informer until ctx is done
+func (pm *PodManager) SyncWorkerPods(ctx context.Context) error {
+	lw := cache.NewFilteredListWatchFromClient(pm.clientset.CoreV1().RESTClient(), "pods", pm.namespace,
+		func(options *metav1.ListOptions) {
+			options.LabelSelector = WorkerSelector
+		})
+	_, informer := cache.NewInformerWithOptions(cache.InformerOptions{
+		ListerWatcher: lw,
+		ObjectType:    &corev1.Pod{},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				if pod, ok := obj.(*corev1.Pod); ok {
+					pm.observePod(pod)
+				}
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				if pod, ok := obj.(*corev1.Pod); ok {
+					pm.observePod(pod)
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if pod, ok := obj.(*corev1.Pod); ok {
+					pm.untrackPod(pod.Name)
+				}
+			},
+		},
+	})
+	go informer.RunWithContext(ctx)
+
+	// The informer keeps retrying in the background if the list fails
+	syncCtx, cancel := context.WithTimeout(ctx, podSyncTimeout)
+	defer cancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		return fmt.Errorf("failed to list worker pods: %w", syncCtx.Err())
+	}
+	log.Printf("Tracking %d worker pods in namespace %s", len(pm.GetActivePods()), pm.namespace)
+	return nil
+}
+
+// observePod records the current state of a worker pod. Pods created by
+// another process are added with what their labels and spec tell.
+func (pm *PodManager) observePod(pod *corev1.Pod) {
+	pm.podsMu.Lock()
+	defer pm.podsMu.Unlock()
+
+	// A pod being deleted must not be reused for a new task
+	if pod.DeletionTimestamp != nil {
+		delete(pm.activePods, pod.Name)
+		return
+	}
+
+	// Replace rather than modify the entry; callers may hold the old one
+	if worker, exists := pm.activePods[pod.Name]; exists {
+		updated := *worker
+		updated.Status = pod.Status.Phase
+		pm.activePods[pod.Name] = &updated
+		return
+	}
+
+	issueNumber, _ := strconv.Atoi(pod.Labels["issue"])
+	worker := &WorkerPod{
+		ID:           pod.Name,
+		IssueNumber:  issueNumber,
+		PodName:      pod.Name,
+		JobName:      pod.Labels["job-name"],
+		Manager:      pod.Labels[ManagerLabel],
+		StartTime:    pod.CreationTimestamp.Time,
+		WorkspaceDir: filepath.Join(pm.workspacesDir, fmt.Sprintf("issue-%d", issueNumber)),
+		SessionFile:  filepath.Join(pm.sessionsDir, fmt.Sprintf("issue-%d.session", issueNumber)),
+		Status:       pod.Status.Phase,
+	}
+	// The repository label has its slash replaced, the environment does not
+	if len(pod.Spec.Containers) > 0 {
+		for _, env := range pod.Spec.Containers[0].Env {
+			if env.Name == "REPOSITORY" {
+				worker.Repository = env.Value
+			}
+		}
+	}
+	// Pods created before the annotation existed work on their own issues
+	worker.IssueRepository = pod.Annotations[IssueRepositoryAnnotation]
+	if worker.IssueRepository == "" {
+		worker.IssueRepository = worker.Repository
+	}
+	pm.activePods[pod.Name] = worker
+}
+
+// trackPod records a worker pod created by this PodManager
+func (pm *PodManager) trackPod(worker *WorkerPod) {
+	pm.podsMu.Lock()
+	defer pm.podsMu.Unlock()
+	pm.activePods[worker.PodName] = worker
+}
+
+// trackedPod returns the active pod with the given name
+func (pm *PodManager) trackedPod(podName string) (*WorkerPod, bool) {
+	pm.podsMu.Lock()
+	defer pm.podsMu.Unlock()
+	worker, exists := pm.activePods[podName]
+	return worker, exists
+}
+
+// untrackPod forgets a deleted worker pod
+func (pm *PodManager) untrackPod(podName string) {
+	pm.podsMu.Lock()
+	defer pm.podsMu.Unlock()
+	delete(pm.activePods, podName)
+}
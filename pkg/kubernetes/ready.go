@@ -0,0 +1,214 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// maxPodEvents is how many of a pod's most recent Events a PodStartError quotes
+const maxPodEvents = 10
+
+// fatalWaitingReasons are container waiting reasons a pod does not recover
+// from without a change to its spec, the image or the cluster
+var fatalWaitingReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImageNeverPull":          true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// PodStartError explains why a worker pod did not become ready
+type PodStartError struct {
+	Pod        string
+	Reason     string // e.g. ErrImageNeverPull, Unschedulable or Timeout
+	Message    string
+	Containers []string // states of the containers that are not running
+	Events     []string // the pod's most recent Events, oldest first
+}
+
+func (e *PodStartError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pod %s failed to start: %s", e.Pod, e.Reason)
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if len(e.Containers) > 0 {
+		b.WriteString("\n\nContainers:\n  " + strings.Join(e.Containers, "\n  "))
+	}
+	if len(e.Events) > 0 {
+		b.WriteString("\n\nEvents:\n  " + strings.Join(e.Events, "\n  "))
+	}
+	return b.String()
+}
+
+// WaitForPodReady watches a pod until it is ready. It fails as soon as the
+// pod cannot start, e.g. because its image cannot be pulled, its container
+// keeps crashing or it cannot be scheduled, with the container states and
+// the pod's recent Events.
+func (pm *PodManager) WaitForPodReady(ctx context.Context, podName string, timeout time.Duration) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Printf("Waiting for pod %s to become ready...", podName)
+
+	var last *corev1.Pod
+	lw := cache.NewListWatchFromClient(pm.clientset.CoreV1().RESTClient(), "pods", pm.namespace,
+		fields.OneTermEqualSelector("metadata.name", podName))
+	_, err := watchtools.UntilWithSync(timeoutCtx, lw, &corev1.Pod{}, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			return false, &PodStartError{Pod: podName, Reason: "Deleted", Message: "the pod was deleted while starting"}
+		}
+		pod, ok := event.Object.(*corev1.Pod)
+		if !ok {
+			return false, nil
+		}
+		last = pod
+		if podReady(pod) {
+			return true, nil
+		}
+		if failure := podStartFailure(pod); failure != nil {
+			return false, failure
+		}
+		return false, nil
+	})
+	if err == nil {
+		log.Printf("Pod %s is ready", podName)
+		return nil
+	}
+
+	failure, ok := err.(*PodStartError)
+	if !ok {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if timeoutCtx.Err() == nil {
+			return fmt.Errorf("failed to watch pod %s: %w", podName, err)
+		}
+		// Say what the pod was still waiting for
+		failure = &PodStartError{Pod: podName, Reason: "Timeout", Message: fmt.Sprintf("not ready after %v", timeout)}
+		if last != nil {
+			failure.Containers = containerStates(last)
+		}
+	}
+	failure.Events = pm.podEvents(context.WithoutCancel(ctx), podName)
+	return failure
+}
+
+// podReady reports whether the pod is running with all containers ready
+func podReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podStartFailure returns why the pod will not start, or nil while it may
+// still do so
+func podStartFailure(pod *corev1.Pod) *PodStartError {
+	failure := &PodStartError{Pod: pod.Name, Containers: containerStates(pod)}
+
+	switch pod.Status.Phase {
+	case corev1.PodFailed, corev1.PodSucceeded:
+		failure.Reason, failure.Message = pod.Status.Reason, pod.Status.Message
+		if failure.Reason == "" {
+			failure.Reason = string(pod.Status.Phase)
+		}
+		return failure
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == corev1.PodReasonUnschedulable {
+			failure.Reason, failure.Message = condition.Reason, condition.Message
+			return failure
+		}
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil && fatalWaitingReasons[waiting.Reason] {
+			failure.Reason, failure.Message = waiting.Reason, waiting.Message
+			return failure
+		}
+	}
+	return nil
+}
+
+// containerStates describes the containers of a pod that are not running
+func containerStates(pod *corev1.Pod) []string {
+	var states []string
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		switch {
+		case status.State.Waiting != nil:
+			states = append(states, strings.TrimSuffix(fmt.Sprintf("%s: waiting: %s: %s",
+				status.Name, status.State.Waiting.Reason, status.State.Waiting.Message), ": "))
+		case status.State.Terminated != nil:
+			terminated := status.State.Terminated
+			states = append(states, strings.TrimSuffix(fmt.Sprintf("%s: terminated: %s (exit code %d): %s",
+				status.Name, terminated.Reason, terminated.ExitCode, terminated.Message), ": "))
+		}
+	}
+	return states
+}
+
+// podEvents returns the pod's most recent Events, oldest first. They only
+// add detail to an error, so a failure to list them is logged.
+func (pm *PodManager) podEvents(ctx context.Context, podName string) []string {
+	list, err := pm.clientset.CoreV1().Events(pm.namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.Set{"involvedObject.kind": "Pod", "involvedObject.name": podName}.String(),
+	})
+	if err != nil {
+		log.Printf("Warning: failed to list events of pod %s: %v", podName, err)
+		return nil
+	}
+
+	items := list.Items
+	sort.Slice(items, func(i, j int) bool {
+		return eventTime(items[i]).Before(eventTime(items[j]))
+	})
+	if len(items) > maxPodEvents {
+		items = items[len(items)-maxPodEvents:]
+	}
+
+	events := make([]string, 0, len(items))
+	for _, event := range items {
+		line := fmt.Sprintf("%s %s: %s", event.Type, event.Reason, strings.TrimSpace(event.Message))
+		if event.Count > 1 {
+			line += fmt.Sprintf(" (x%d)", event.Count)
+		}
+		events = append(events, line)
+	}
+	return events
+}
+
+// eventTime is when an Event last occurred
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
@@ -0,0 +1,72 @@
+package orchestrator
+
+import "time"
+
+// TaskState is the lifecycle state of a task submitted to the orchestrator
+type TaskState string
+
+const (
+	StateQueued    TaskState = "queued"
+	StateRunning   TaskState = "running"
+	StateSucceeded TaskState = "succeeded"
+	StateFailed    TaskState = "failed"
+	StateCancelled TaskState = "cancelled"
+)
+
+// Done reports whether a task in this state has finished
+func (s TaskState) Done() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCancelled
+}
+
+// TaskRequest is the body of POST /tasks
+type TaskRequest struct {
+	IssueNumber      int      `json:"issue_number"`
+	Task             string   `json:"task"`
+	Repository       string   `json:"repository"`                  // target repository
+	SourceRepository string   `json:"source_repository,omitempty"` // repository of the issue; GITHUB_OWNER/GITHUB_REPO when empty
+	Ref              string   `json:"ref,omitempty"`               // branch, tag or commit; taken from the task or the default branch when empty
+	MaxTurns         int      `json:"max_turns,omitempty"`
+	FollowUp         string   `json:"follow_up,omitempty"` // resumes the issue's previous Claude session with this prompt
+	RequestedBy      string   `json:"requested_by,omitempty"`
+	Labels           []string `json:"labels,omitempty"` // issue labels; select repo-mapping rules
+}
+
+// Task is the status of a submitted task, returned by POST /tasks and
+// GET /tasks/{id}
+type Task struct {
+	ID          string      `json:"id"`
+	Request     TaskRequest `json:"request"`
+	State       TaskState   `json:"state"`
+	Worker      string      `json:"worker,omitempty"` // pod or container running the task
+	Error       string      `json:"error,omitempty"`
+	CancelledBy string      `json:"cancelled_by,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	StartedAt   *time.Time  `json:"started_at,omitempty"`
+	FinishedAt  *time.Time  `json:"finished_at,omitempty"`
+}
+
+// TaskResult is the outcome of a finished task, returned by
+// GET /tasks/{id}/result
+type TaskResult struct {
+	ID             string    `json:"id"`
+	State          TaskState `json:"state"`
+	Subtype        string    `json:"subtype,omitempty"`
+	IsError        bool      `json:"is_error"`
+	Message        string    `json:"message,omitempty"` // final assistant message
+	NumTurns       int       `json:"num_turns"`
+	DurationMS     int64     `json:"duration_ms"`
+	CostUSD        float64   `json:"cost_usd"`
+	SessionID      string    `json:"session_id,omitempty"`
+	PullRequestURL string    `json:"pull_request_url,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// CancelRequest is the body of POST /tasks/{id}/cancel
+type CancelRequest struct {
+	CancelledBy string `json:"cancelled_by,omitempty"`
+}
+
+// ErrorResponse is returned with every non-2xx status
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,118 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// APIError is a non-2xx response from the orchestrator
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("orchestrator returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the orchestrator HTTP API
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the orchestrator at baseURL. When token is
+// set it is sent as a bearer token.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Submit starts a task
+func (c *Client) Submit(ctx context.Context, request TaskRequest) (*Task, error) {
+	var task Task
+	if err := c.do(ctx, http.MethodPost, "/tasks", request, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Get returns the status of a task
+func (c *Client) Get(ctx context.Context, id string) (*Task, error) {
+	var task Task
+	if err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id), nil, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Cancel stops a queued or running task
+func (c *Client) Cancel(ctx context.Context, id, cancelledBy string) (*Task, error) {
+	var task Task
+	if err := c.do(ctx, http.MethodPost, "/tasks/"+url.PathEscape(id)+"/cancel", CancelRequest{CancelledBy: cancelledBy}, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// Result returns the outcome of a finished task
+func (c *Client) Result(ctx context.Context, id string) (*TaskResult, error) {
+	var result TaskResult
+	if err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id)+"/result", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call orchestrator: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr ErrorResponse
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(data))
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode orchestrator response: %w", err)
+	}
+	return nil
+}
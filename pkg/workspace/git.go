@@ -0,0 +1,228 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CloneOptions describes how a repository is checked out into a worker
+type CloneOptions struct {
+	Repository string // owner/name
+	Ref        string // branch, tag or commit SHA
+	Dir        string // checkout directory inside the worker
+	Depth      int    // 0 fetches the full history
+	Submodules bool
+}
+
+var refRegex = regexp.MustCompile(`(?im)^\s*(?:ref|branch):\s*([^\s]+)`)
+
+// RefFromTask returns a ref named in a task with a "ref: <name>" or
+// "branch: <name>" line
+func RefFromTask(task string) string {
+	if matches := refRegex.FindStringSubmatch(task); len(matches) > 1 {
+		return matches[1]
+	}
+	return ""
+}
+
+// CloneOptionsFromEnv applies CLONE_DEPTH (default 1) and CLONE_SUBMODULES
+// (default true) to a checkout
+func CloneOptionsFromEnv(repository, ref, dir string) (CloneOptions, error) {
+	opts := CloneOptions{
+		Repository: repository,
+		Ref:        ref,
+		Dir:        dir,
+		Depth:      1,
+		Submodules: true,
+	}
+
+	if value := os.Getenv("CLONE_DEPTH"); value != "" {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return opts, fmt.Errorf("invalid CLONE_DEPTH %q (expected 0 or a positive integer)", value)
+		}
+		opts.Depth = depth
+	}
+	if value := os.Getenv("CLONE_SUBMODULES"); value != "" {
+		submodules, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid CLONE_SUBMODULES %q: %w", value, err)
+		}
+		opts.Submodules = submodules
+	}
+	return opts, nil
+}
+
+// CloneScript returns a shell script that checks out the repository. The
+// token is read from $GITHUB_TOKEN in the worker through a credential helper,
+// so it never appears in the command line or in logs.
+func CloneScript(opts CloneOptions) string {
+	depth := ""
+	if opts.Depth > 0 {
+		depth = " --depth " + strconv.Itoa(opts.Depth)
+	}
+	url := "https://github.com/" + opts.Repository + ".git"
+
+	lines := []string{
+		"set -e",
+		`git config --global credential.helper '!f() { echo username=x-access-token; echo "password=${GITHUB_TOKEN}"; }; f'`,
+		"mkdir -p " + ShellQuote(opts.Dir),
+		"cd " + ShellQuote(opts.Dir),
+		"git init -q",
+		"git remote remove origin >/dev/null 2>&1 || true",
+		"git remote add origin " + ShellQuote(url),
+		// Fetching the ref directly works for branches, tags and commit SHAs
+		"git fetch -q" + depth + " origin " + ShellQuote(opts.Ref),
+		"git -c advice.detachedHead=false checkout -q --detach FETCH_HEAD",
+		// Remember the checkout so commits made by Claude itself are published too
+		"git update-ref " + baseRef + " HEAD",
+	}
+	if opts.Submodules {
+		lines = append(lines, "git submodule update -q --init --recursive"+depth)
+	}
+	lines = append(lines, `echo "Checked out $(git rev-parse HEAD)"`)
+
+	return strings.Join(lines, "\n")
+}
+
+// ShellQuote quotes s for use as a single sh argument
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// baseRef records the commit CloneScript checked out
+const baseRef = "refs/claude/base"
+
+// Markers printed by PublishScript and TaskScript
+const (
+	markerNoChanges   = "CLAUDE_NO_CHANGES"
+	markerPushed      = "CLAUDE_PUSHED "
+	markerCloneFailed = "CLAUDE_CLONE_FAILED"
+)
+
+// PublishOptions describes how workspace changes are committed and pushed
+type PublishOptions struct {
+	Dir         string
+	Branch      string
+	Message     string
+	AuthorName  string
+	AuthorEmail string
+}
+
+// PublishResult reports what PublishScript did
+type PublishResult struct {
+	Changed bool
+	Commit  string
+}
+
+// IssueBranch is the branch workspace changes for an issue are pushed to
+func IssueBranch(issueNumber int) string {
+	return fmt.Sprintf("claude/issue-%d", issueNumber)
+}
+
+// NewPublishOptions fills in the commit author from GIT_AUTHOR_NAME and
+// GIT_AUTHOR_EMAIL
+func NewPublishOptions(dir, branch, message string) PublishOptions {
+	opts := PublishOptions{
+		Dir:         dir,
+		Branch:      branch,
+		Message:     message,
+		AuthorName:  os.Getenv("GIT_AUTHOR_NAME"),
+		AuthorEmail: os.Getenv("GIT_AUTHOR_EMAIL"),
+	}
+	if opts.AuthorName == "" {
+		opts.AuthorName = "Claude Automation"
+	}
+	if opts.AuthorEmail == "" {
+		opts.AuthorEmail = "claude-automation@users.noreply.github.com"
+	}
+	return opts
+}
+
+// PublishScript returns a shell script that commits a dirty workspace to the
+// branch and pushes it, using the credential helper set up by CloneScript
+func PublishScript(opts PublishOptions) string {
+	return strings.Join([]string{
+		"set -e",
+		"cd " + ShellQuote(opts.Dir),
+		`if [ -z "$(git status --porcelain)" ] && [ "$(git rev-parse HEAD)" = "$(git rev-parse ` + baseRef + `)" ]; then echo ` + markerNoChanges + `; exit 0; fi`,
+		"git checkout -q -B " + ShellQuote(opts.Branch),
+		"git add -A",
+		`if [ -n "$(git status --porcelain)" ]; then git -c user.name=` + ShellQuote(opts.AuthorName) + " -c user.email=" + ShellQuote(opts.AuthorEmail) +
+			" commit -q -m " + ShellQuote(opts.Message) + "; fi",
+		"git push -q origin " + ShellQuote("HEAD:refs/heads/"+opts.Branch),
+		`echo "` + markerPushed + `$(git rev-parse HEAD)"`,
+	}, "\n")
+}
+
+// ParsePublishOutput reads the result of PublishScript from its output
+func ParsePublishOutput(output string) (PublishResult, error) {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == markerNoChanges:
+			return PublishResult{}, nil
+		case strings.HasPrefix(line, markerPushed):
+			return PublishResult{Changed: true, Commit: strings.TrimPrefix(line, markerPushed)}, nil
+		}
+	}
+	return PublishResult{}, fmt.Errorf("unexpected publish output: %s", strings.TrimSpace(output))
+}
+
+// TaskScript returns the entrypoint of a worker that runs a whole task by
+// itself: CloneScript, the setup command, claudeCommand and, when Claude
+// exits successfully, PublishScript. Only Claude writes to stdout. The
+// publish markers, or a marker for a failed checkout, are written to
+// resultFile, e.g. the container's termination message path.
+func TaskScript(clone CloneOptions, setup, claudeCommand string, publish PublishOptions, resultFile string) string {
+	result := ShellQuote(resultFile)
+	// The scripts run in subshells of their own: set -e is ignored in a
+	// subshell that is part of a || list
+	lines := []string{
+		"(\n" + CloneScript(clone) + "\n) >&2",
+		`if [ "$?" -ne 0 ]; then echo ` + markerCloneFailed + " > " + result + "; exit 1; fi",
+	}
+	if setup != "" {
+		// Claude can still work without the setup command, as in the exec flow
+		lines = append(lines, "(cd "+ShellQuote(clone.Dir)+" && "+setup+") >&2 || echo 'Warning: setup command failed' >&2")
+	}
+	lines = append(lines,
+		"cd "+ShellQuote(clone.Dir),
+		// sh as pid 1 ignores SIGTERM, so pass it on to Claude when the worker is deleted
+		`trap 'kill -TERM "$pid" 2>/dev/null' TERM`,
+		claudeCommand+" </dev/null &",
+		"pid=$!",
+		`wait "$pid"`,
+		"status=$?",
+		`if [ "$status" -eq 0 ]; then`,
+		"(\n"+PublishScript(publish)+"\n) > "+result,
+		"status=$?",
+		"fi",
+		`exit "$status"`,
+	)
+	return strings.Join(lines, "\n")
+}
+
+// TaskScriptResult is what TaskScript left in its result file
+type TaskScriptResult struct {
+	CloneFailed bool
+	Published   bool // PublishScript finished; Publish holds its result
+	Publish     PublishResult
+}
+
+// ParseTaskScriptResult reads the result file written by TaskScript
+func ParseTaskScriptResult(output string) TaskScriptResult {
+	var result TaskScriptResult
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == markerCloneFailed {
+			result.CloneFailed = true
+		}
+	}
+	if publish, err := ParsePublishOutput(output); err == nil {
+		result.Published, result.Publish = true, publish
+	}
+	return result
+}
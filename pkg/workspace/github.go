@@ -0,0 +1,203 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// GitHub resolves checkouts and manages the pull requests of issue branches
+type GitHub struct {
+	Client *github.Client
+	// Call wraps every API call, e.g. for rate limit handling; optional
+	Call func(ctx context.Context, call func() (*github.Response, error)) error
+}
+
+func (g *GitHub) call(ctx context.Context, call func() (*github.Response, error)) error {
+	if g.Call != nil {
+		return g.Call(ctx, call)
+	}
+	_, err := call()
+	return err
+}
+
+// Checkout is the ref to clone and the base branch for a pull request
+type Checkout struct {
+	Ref  string
+	Base string
+}
+
+// ResolveCheckout decides what to check out for an issue. Without an
+// explicit ref, an existing issue branch is continued so later runs update
+// the same pull request; otherwise the default branch is used. An explicit
+// ref that is a branch also becomes the pull request base.
+func (g *GitHub) ResolveCheckout(ctx context.Context, repository, ref string, issueNumber int) (Checkout, error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok {
+		return Checkout{}, fmt.Errorf("invalid repository %q (expected owner/name)", repository)
+	}
+
+	var repo *github.Repository
+	err := g.call(ctx, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		repo, resp, err = g.Client.Repositories.Get(ctx, owner, name)
+		return resp, err
+	})
+	if err != nil {
+		return Checkout{}, fmt.Errorf("failed to get repository %s: %w", repository, err)
+	}
+	checkout := Checkout{Ref: ref, Base: repo.GetDefaultBranch()}
+
+	if ref != "" {
+		isBranch, err := g.branchExists(ctx, owner, name, ref)
+		if err != nil {
+			return Checkout{}, err
+		}
+		if isBranch && ref != IssueBranch(issueNumber) {
+			checkout.Base = ref
+		}
+		return checkout, nil
+	}
+
+	exists, err := g.branchExists(ctx, owner, name, IssueBranch(issueNumber))
+	if err != nil {
+		return Checkout{}, err
+	}
+	if exists {
+		checkout.Ref = IssueBranch(issueNumber)
+	} else {
+		checkout.Ref = checkout.Base
+	}
+	return checkout, nil
+}
+
+func (g *GitHub) branchExists(ctx context.Context, owner, name, branch string) (bool, error) {
+	var resp *github.Response
+	err := g.call(ctx, func() (*github.Response, error) {
+		var err error
+		_, resp, err = g.Client.Repositories.GetBranch(ctx, owner, name, branch, 0)
+		return resp, err
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get branch %s: %w", branch, err)
+	}
+	return true, nil
+}
+
+// Language returns the primary language GitHub detected for a repository,
+// or "" when it has none
+func (g *GitHub) Language(ctx context.Context, repository string) (string, error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok {
+		return "", fmt.Errorf("invalid repository %q (expected owner/name)", repository)
+	}
+
+	var repo *github.Repository
+	err := g.call(ctx, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		repo, resp, err = g.Client.Repositories.Get(ctx, owner, name)
+		return resp, err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get repository %s: %w", repository, err)
+	}
+	return repo.GetLanguage(), nil
+}
+
+// EnsurePullRequest opens a pull request for the branch, or updates the body
+// of the one that is already open. It reports whether a new one was created.
+func (g *GitHub) EnsurePullRequest(ctx context.Context, repository, branch, base, title, body string) (*github.PullRequest, bool, error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok {
+		return nil, false, fmt.Errorf("invalid repository %q (expected owner/name)", repository)
+	}
+
+	var existing []*github.PullRequest
+	err := g.call(ctx, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		existing, resp, err = g.Client.PullRequests.List(ctx, owner, name, &github.PullRequestListOptions{
+			State: "open",
+			Head:  owner + ":" + branch,
+		})
+		return resp, err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	if len(existing) > 0 {
+		pr := existing[0]
+		err := g.call(ctx, func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			pr, resp, err = g.Client.PullRequests.Edit(ctx, owner, name, pr.GetNumber(), &github.PullRequest{Body: &body})
+			return resp, err
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to update pull request #%d: %w", existing[0].GetNumber(), err)
+		}
+		return pr, false, nil
+	}
+
+	var pr *github.PullRequest
+	err = g.call(ctx, func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		pr, resp, err = g.Client.PullRequests.Create(ctx, owner, name, &github.NewPullRequest{
+			Title: &title,
+			Head:  &branch,
+			Base:  &base,
+			Body:  &body,
+		})
+		return resp, err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	return pr, true, nil
+}
+
+// PullRequestTitle builds the title of an issue branch's pull request
+func PullRequestTitle(issueRef, task string) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(task), "\n", 2)[0])
+	if runes := []rune(line); len(runes) > 60 {
+		line = string(runes[:60]) + "…"
+	}
+	if line == "" {
+		return "Claude: " + issueRef
+	}
+	return fmt.Sprintf("Claude: %s (%s)", line, issueRef)
+}
+
+// PullRequestBody builds the body of an issue branch's pull request from
+// the task and Claude's summary. issueRef is "#n" or "owner/repo#n".
+func PullRequestBody(issueRef, task, summary string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Refs %s\n\n", issueRef)
+	b.WriteString("## タスク\n\n")
+	for _, line := range strings.Split(strings.TrimSpace(task), "\n") {
+		b.WriteString("> " + line + "\n")
+	}
+	b.WriteString("\n## Claudeによる作業サマリー\n\n")
+	if summary = strings.TrimSpace(summary); summary != "" {
+		b.WriteString(summary + "\n")
+	} else {
+		b.WriteString("(サマリーはありません)\n")
+	}
+	b.WriteString("\n---\n🤖 このPull Requestは `@claude` への依頼から自動作成されました。内容を確認してからマージしてください。\n")
+	return b.String()
+}
+
+// CommitMessage builds the commit message for an issue's workspace changes
+func CommitMessage(issueRef, task string) string {
+	return PullRequestTitle(issueRef, task) + "\n\nRequested in " + issueRef
+}
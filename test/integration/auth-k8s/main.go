@@ -125,7 +125,7 @@ func main() {
 	}
 	
 	issueNumber := 16 // Use issue 16 for auth testing
-	workerPod, err := podManager.CreateWorkerPod(ctx, issueNumber, "test/auth-integration", podConfig)
+	workerPod, err := podManager.CreateWorkerPod(ctx, issueNumber, "test/auth-integration", "test/auth-integration", podConfig)
 	if err != nil {
 		log.Printf("❌ Failed to create worker pod: %v", err)
 		return
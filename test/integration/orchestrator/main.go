@@ -35,7 +35,7 @@ func main() {
 		Env:       []string{"NODE_ENV=development", "TEST_MODE=true"},
 	}
 	
-	workerPod, err := podManager.CreateWorkerPod(ctx, issueNumber, repository, config)
+	workerPod, err := podManager.CreateWorkerPod(ctx, issueNumber, repository, repository, config)
 	if err != nil {
 		log.Fatalf("❌ Failed to create worker pod: %v", err)
 	}